@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// cloneRepo shallow clones repo at ref (the default branch if empty) into a
+// temporary directory. repo may end with //path to use a subdirectory, like
+// Terraform module sources. It returns the configuration directory and a
+// function that removes the clone. The clone is made in parent, or the OS
+// temporary directory if parent is empty.
+func cloneRepo(ctx context.Context, repo string, ref string, parent string) (string, func(), error) {
+	url, subdir := splitRepoSubdir(repo)
+
+	tmpDir, err := os.MkdirTemp(parent, "rover-git")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	// Fetching a single ref works for branches, tags and commits. -- ends
+	// the options, so a url or ref like --upload-pack=... isn't one.
+	cmds := [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "--", "origin", url},
+		{"fetch", "--quiet", "--depth", "1", "--", "origin", ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	}
+	if ref == "" {
+		cmds = [][]string{{"clone", "--quiet", "--depth", "1", "--", url, "."}}
+	}
+
+	for _, args := range cmds {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = tmpDir
+		// Fail instead of waiting for credentials
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			cleanup()
+			return "", nil, errors.New(fmt.Sprintf("Unable to clone %s: git %s: %s %s", url, args[0], err, strings.TrimSpace(string(out))))
+		}
+	}
+
+	dir := filepath.Join(tmpDir, subdir)
+	if rel, err := filepath.Rel(tmpDir, dir); err != nil || strings.HasPrefix(rel, "..") {
+		cleanup()
+		return "", nil, errors.New(fmt.Sprintf("Invalid repository subdirectory %s", subdir))
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		cleanup()
+		return "", nil, errors.New(fmt.Sprintf("Repository %s has no directory %s", url, subdir))
+	}
+
+	return dir, cleanup, nil
+}
+
+// splitRepoSubdir splits a repository URL ending with //path into the URL and path
+func splitRepoSubdir(repo string) (string, string) {
+	start := 0
+	if i := strings.Index(repo, "://"); i >= 0 {
+		start = i + len("://")
+	}
+
+	if i := strings.Index(repo[start:], "//"); i >= 0 {
+		return repo[:start+i], repo[start+i+2:]
+	}
+
+	return repo, ""
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitRepo creates a repository with a main.tf committed on main
+func gitRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte("resource \"null_resource\" \"a\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "main.tf"},
+		{"-c", "user.name=rover", "-c", "user.email=rover@example.com", "commit", "--quiet", "-m", "main.tf"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s %s", args[0], err, out)
+		}
+	}
+
+	return dir
+}
+
+func TestCloneRepo(t *testing.T) {
+	repo := gitRepo(t)
+
+	for _, ref := range []string{"", "main"} {
+		dir, cleanup, err := cloneRepo(context.Background(), repo, ref, t.TempDir())
+		if err != nil {
+			t.Fatalf("ref %q: %s", ref, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "main.tf")); err != nil {
+			t.Errorf("ref %q: %s", ref, err)
+		}
+		cleanup()
+	}
+}
+
+// TestCloneRepoOptions checks a url or ref starting with - isn't passed to
+// git as an option, which --upload-pack would run as a command
+func TestCloneRepoOptions(t *testing.T) {
+	repo := gitRepo(t)
+	marker := filepath.Join(t.TempDir(), "marker")
+	uploadPack := "--upload-pack=touch " + marker + ";git-upload-pack"
+
+	tests := []struct {
+		repo string
+		ref  string
+	}{
+		{repo: uploadPack},
+		{repo: uploadPack, ref: "main"},
+		{repo: repo, ref: uploadPack},
+	}
+
+	for _, tt := range tests {
+		_, _, err := cloneRepo(context.Background(), tt.repo, tt.ref, t.TempDir())
+		if err == nil {
+			t.Errorf("cloneRepo(%q, %q) succeeded, want error", tt.repo, tt.ref)
+		}
+		if _, err := os.Stat(marker); err == nil {
+			t.Fatalf("cloneRepo(%q, %q) ran --upload-pack", tt.repo, tt.ref)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	stylesheetLink = regexp.MustCompile(`<link (?:rel="stylesheet" )?href="/([^"]+)"(?: rel="stylesheet")?>`)
+	preloadLink    = regexp.MustCompile(`<link href="[^"]+" rel="preload" as="[a-z]+">`)
+	faviconLink    = regexp.MustCompile(`<link rel="icon" href="/([^"]+)">`)
+	scriptTag      = regexp.MustCompile(`<script src="/([^"]+)"></script>`)
+	// Images the frontend loads relative to its public path
+	publicPathImage = regexp.MustCompile(`r\.p\+"(img/[^"]+)"`)
+	sourceMap       = regexp.MustCompile(`(?m)^//# sourceMappingURL=.*$`)
+)
+
+// generateHTML writes the frontend and the plan, rso, map and graph as a
+// single HTML file that opens in a browser without Rover. Like the standalone
+// zip, the frontend reads the assets from global variables instead of the API.
+func (r *app) generateHTML(fe fs.FS, filename string) error {
+	index, err := fs.ReadFile(fe, "index.html")
+	if err != nil {
+		return err
+	}
+
+	var inlineErr error
+	inline := func(re *regexp.Regexp, s string, f func(b []byte, name string) string) string {
+		return re.ReplaceAllStringFunc(s, func(m string) string {
+			name := re.FindStringSubmatch(m)[1]
+			b, err := fs.ReadFile(fe, name)
+			if err != nil {
+				inlineErr = err
+				return m
+			}
+			return f(b, name)
+		})
+	}
+
+	html := preloadLink.ReplaceAllString(string(index), "")
+	html = inline(faviconLink, html, func(b []byte, name string) string {
+		return fmt.Sprintf(`<link rel="icon" href="%s">`, dataURI(b, name))
+	})
+	html = inline(stylesheetLink, html, func(b []byte, name string) string {
+		return fmt.Sprintf("<style>%s</style>", b)
+	})
+	html = inline(scriptTag, html, func(b []byte, name string) string {
+		js := sourceMap.ReplaceAllString(string(b), "")
+		js = inline(publicPathImage, js, func(b []byte, name string) string {
+			return fmt.Sprintf("%q", dataURI(b, name))
+		})
+		return fmt.Sprintf("<script>%s</script>", escapeScript(js))
+	})
+	if inlineErr != nil {
+		return inlineErr
+	}
+
+	// Add the assets before the frontend's scripts
+	assets := []struct {
+		fileType string
+		j        interface{}
+	}{
+		{"plan", r.Plan},
+		{"rso", r.RSO},
+		{"map", r.Map},
+		{"graph", r.Graph},
+	}
+
+	var data strings.Builder
+	for _, a := range assets {
+		// Marshal escapes <, > and & so the JSON can't close the script
+		b, err := json.Marshal(a.j)
+		if err != nil {
+			return fmt.Errorf("Error producing %s JSON: %s", a.fileType, err)
+		}
+		fmt.Fprintf(&data, "<script>const %s = %s</script>", a.fileType, b)
+	}
+
+	parts := strings.SplitN(html, "</head>", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("Unable to find </head> in index.html")
+	}
+	html = parts[0] + data.String() + "</head>" + parts[1]
+
+	return os.WriteFile(filename, []byte(html), 0644)
+}
+
+// dataURI returns b as a base64 data URI with the MIME type of name
+func dataURI(b []byte, name string) string {
+	mimeType := mime.TypeByExtension(path.Ext(name))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(b))
+}
+
+// escapeScript keeps inlined JavaScript from closing its script tag
+func escapeScript(js string) string {
+	return strings.ReplaceAll(js, "</script", `<\/script`)
+}
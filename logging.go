@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// setupLogging sets the default logger. level is debug, info, warn or error
+// and format is text or json.
+func setupLogging(level string, format string) error {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(level))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid logLevel %q: must be debug, info, warn or error", level))
+	}
+
+	opts := &slog.HandlerOptions{Level: l}
+
+	var h slog.Handler
+	switch format {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return errors.New(fmt.Sprintf("Invalid logFormat %q: must be text or json", format))
+	}
+
+	slog.SetDefault(slog.New(h))
+
+	return nil
+}
+
+// Functions run before Rover exits
+var cleanups []func()
+
+// atExit registers f to run before Rover exits
+func atExit(f func()) {
+	cleanups = append(cleanups, f)
+}
+
+// runCleanups runs the functions registered with atExit, last first
+func runCleanups() {
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+	cleanups = nil
+}
+
+// exit runs the registered cleanup functions and exits with code
+func exit(code int) {
+	runCleanups()
+	os.Exit(code)
+}
+
+// fatal logs err and exits
+func fatal(err error) {
+	slog.Error(err.Error())
+	exit(exitConfigError)
+}
@@ -2,29 +2,43 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
-	"io/ioutil"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
-	tfe "github.com/hashicorp/go-tfe"
-	"github.com/hashicorp/terraform-config-inspect/tfconfig"
 	"github.com/hashicorp/terraform-exec/tfexec"
-	tfjson "github.com/hashicorp/terraform-json"
+
+	"rover/pkg/rover"
 )
 
 const VERSION = "0.3.0"
 
-var TRUE = true
+// API_VERSION is bumped whenever the API's assets change incompatibly
+const API_VERSION = "1"
+
+// Exit codes
+const (
+	exitConfigError = 1
+	exitPlanError   = 2
+	// The plan has a change -failOn fails on
+	exitFailOn = 3
+)
 
 //go:embed ui/dist
 var frontend embed.FS
@@ -44,49 +58,165 @@ func (i *arrayFlags) Set(value string) error {
 	return nil
 }
 
-type rover struct {
-	Name             string
-	WorkingDir       string
-	TfPath           string
-	TfVarsFiles      []string
-	TfVars           []string
-	TfBackendConfigs []string
-	PlanPath         string
-	PlanJSONPath     string
-	WorkspaceName    string
-	TFCOrgName       string
-	TFCWorkspaceName string
-	ShowSensitive    bool
-	GenImage         bool
-	TFCNewRun        bool
-	Plan             *tfjson.Plan
-	RSO              *ResourcesOverview
-	Map              *Map
-	Graph            Graph
+// anonymizeFlag is -anonymize, which is a boolean flag that can also be set
+// to reversible
+type anonymizeFlag string
+
+func (a anonymizeFlag) String() string {
+	return string(a)
+}
+
+func (a *anonymizeFlag) Set(value string) error {
+	switch value {
+	case "true", "reversible":
+		*a = anonymizeFlag(value)
+	case "false":
+		*a = ""
+	default:
+		return errors.New("must be true, false or reversible")
+	}
+	return nil
+}
+
+func (a anonymizeFlag) IsBoolFlag() bool {
+	return true
+}
+
+// tfVarFlag is -tfVar or -tfVarsFile, which add to the same list so the
+// variables and files keep the order they're given in, like in Terraform
+type tfVarFlag struct {
+	tfVars *[]rover.TfVar
+	file   bool
+}
+
+func (f tfVarFlag) String() string {
+	if f.tfVars == nil {
+		return ""
+	}
+	var ts []string
+	for _, v := range *f.tfVars {
+		if v.File == f.file {
+			ts = append(ts, v.Value)
+		}
+	}
+	return strings.Join(ts, ",")
+}
+
+func (f tfVarFlag) Set(value string) error {
+	*f.tfVars = append(*f.tfVars, rover.TfVar{Value: value, File: f.file})
+	return nil
+}
+
+// app is the Rover CLI and server state
+type app struct {
+	*rover.Rover
+	CorsOrigins []string
+	AuthToken   string
+	BasePath    string
+	TLSCert     string
+	TLSKey      string
+	// Server timeouts, 0 disables them
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// Only serve the API, not the frontend
+	APIOnly     bool
+	GenImage    bool
+	Watch       bool
+	Ready       bool
+	metrics     *metrics
+	cache       map[string]*cachedAsset
+	hiddenCache map[string]*cachedAsset
+	changedPlan *cachedAsset
+	etag        string
 }
 
 func main() {
-	var tfPath, workingDir, name, zipFileName, ipPort, planPath, planJSONPath, workspaceName, tfcOrgName, tfcWorkspaceName string
-	var standalone, genImage, showSensitive, getVersion, tfcNewRun bool
-	var tfVarsFiles, tfVars, tfBackendConfigs arrayFlags
-	flag.StringVar(&tfPath, "tfPath", "/usr/local/bin/terraform", "Path to Terraform binary")
-	flag.StringVar(&workingDir, "workingDir", ".", "Path to Terraform configuration")
-	flag.StringVar(&name, "name", "rover", "Configuration name")
+	var tfPath, zipFileName, outputDir, graphOut, mermaidOut, svgOut, ipPort, bindAddr, corsOrigin, authToken, tlsCert, tlsKey, planPath, planJSONPath, comparePlanPath, workspaceName, tfcOrgName, tfcWorkspaceName, tfcRunID, tfcToken, logLevel, logFormat, gitRepo, gitRef, exportHTML, uiDir, tmpDir, basePath, summaryOut, failOn, configDir, tfLog string
+	var stdout, emitEmpty, apiOnly, strictVersion, standalone, genImage, showSensitive, getVersion, tfcNewRun, skipInit, upgrade, destroy, refresh, serve, watch, fromState, collapseInstances, hideNoOp, enableMetrics, quiet, check bool
+	var port, maxDepth, collapseThreshold, retries, parallelism int
+	var timeout, lockTimeoutDuration, readTimeout, writeTimeout, idleTimeout time.Duration
+	var anonymize anonymizeFlag
+	var tfVars []rover.TfVar
+	var envs, workingDirs, names, tfBackendConfigs, targets, filters, includeTypes, excludeTypes arrayFlags
+	flag.StringVar(&tfPath, "tfPath", "", "Path to Terraform or OpenTofu binary (defaults to tofu or terraform on PATH)")
+	flag.Var(&workingDirs, "workingDir", "Path to Terraform configuration (defaults to the current directory, can be repeated with -name to serve multiple configurations)")
+	flag.StringVar(&configDir, "configDir", "", "Root module directory relative to -workingDir, for configurations in a subdirectory of the repository")
+	flag.StringVar(&gitRepo, "gitRepo", "", "Git repository to clone and use as the working directory (append //path for a subdirectory)")
+	flag.StringVar(&gitRef, "gitRef", "", "Branch, tag or commit of -gitRepo to check out (defaults to the default branch)")
+	flag.Var(&names, "name", "Configuration name (defaults to rover, repeat it for each -workingDir)")
 	flag.StringVar(&zipFileName, "zipFileName", "rover", "Standalone zip file name")
-	flag.StringVar(&ipPort, "ipPort", "0.0.0.0:9000", "IP and port for Rover server")
+	flag.StringVar(&outputDir, "outputDir", "", "Directory to write plan, rso, map, graph and meta JSON files to (- writes them to stdout like -stdout)")
+	flag.BoolVar(&stdout, "stdout", false, "Write the assets to stdout as a single JSON object and exit")
+	flag.BoolVar(&emitEmpty, "emitEmpty", false, "Write the assets in headless mode even if the plan has no changes")
+	flag.StringVar(&graphOut, "graphOut", "", "File to write the graph to in Graphviz DOT format")
+	flag.StringVar(&mermaidOut, "mermaidOut", "", "File to write the graph to as a Mermaid diagram")
+	flag.StringVar(&svgOut, "svgOut", "", "File to write the graph to as an SVG image")
+	flag.StringVar(&summaryOut, "summaryOut", "", "File to write the one-line JSON change summary to")
+	flag.StringVar(&failOn, "failOn", "", "Comma-separated changes to exit with code 3 on in headless mode (add, change, destroy, replace or any)")
+	flag.StringVar(&exportHTML, "exportHTML", "", "File to write Rover to as a single self-contained HTML file")
+	flag.BoolVar(&apiOnly, "apiOnly", false, "Only serve the API, without the frontend, for API-only deployments")
+	flag.StringVar(&uiDir, "uiDir", "", "Directory to serve the frontend from instead of the embedded build, for frontend development")
+	flag.StringVar(&tmpDir, "tmpDir", "", "Directory for temporary files, like the plan file and -gitRepo clones (defaults to the OS temporary directory)")
+	flag.StringVar(&ipPort, "ipPort", "", "IP and port for Rover server (overrides -bindAddr and -port)")
+	flag.StringVar(&bindAddr, "bindAddr", "127.0.0.1", "IP address for Rover server to listen on")
+	flag.IntVar(&port, "port", 9000, "Port for Rover server")
+	flag.StringVar(&basePath, "basePath", "", "Path prefix to serve Rover under, like /rover when hosted behind a reverse proxy")
+	flag.DurationVar(&readTimeout, "readTimeout", 30*time.Second, "Maximum duration for reading a request to the Rover server (0 disables the timeout)")
+	flag.DurationVar(&writeTimeout, "writeTimeout", 5*time.Minute, "Maximum duration for writing a response, raise it for very large graphs (0 disables the timeout)")
+	flag.DurationVar(&idleTimeout, "idleTimeout", 2*time.Minute, "Maximum duration to keep idle connections open (0 disables the timeout)")
+	flag.IntVar(&collapseThreshold, "collapseThreshold", 0, "Collapse resource types with at least this many resources into one graph node (0 disables collapsing)")
+	flag.IntVar(&maxDepth, "maxDepth", 0, "Number of nested module levels to show, deeper modules are collapsed (0 shows all)")
+	flag.StringVar(&corsOrigin, "corsOrigin", "", "Comma-separated list of origins allowed to access the API (* allows all)")
+	flag.StringVar(&authToken, "authToken", "", "Token required to access Rover (as a bearer token or token query parameter)")
+	flag.StringVar(&tlsCert, "tlsCert", "", "TLS certificate file, serves Rover over HTTPS with -tlsKey")
+	flag.StringVar(&tlsKey, "tlsKey", "", "TLS private key file, serves Rover over HTTPS with -tlsCert")
 	flag.StringVar(&planPath, "planPath", "", "Plan file path")
 	flag.StringVar(&planJSONPath, "planJSONPath", "", "Plan JSON file path")
+	flag.BoolVar(&strictVersion, "strictVersion", false, "Fail instead of warning if the plan's format version isn't supported")
+	flag.StringVar(&comparePlanPath, "comparePlan", "", "Plan or plan JSON file to compare the plan to")
 	flag.StringVar(&workspaceName, "workspaceName", "", "Workspace name")
+	flag.StringVar(&workspaceName, "workspace", "", "Workspace name, like -workspaceName")
 	flag.StringVar(&tfcOrgName, "tfcOrg", "", "Terraform Cloud Organization name")
 	flag.StringVar(&tfcWorkspaceName, "tfcWorkspace", "", "Terraform Cloud Workspace name")
+	flag.StringVar(&tfcRunID, "tfcRunID", "", "Terraform Cloud run ID, like run-abc123, to visualize the plan of")
+	flag.StringVar(&tfcToken, "tfcToken", "", "Terraform Cloud API token (defaults to the TFC_TOKEN environment variable)")
+	flag.StringVar(&logLevel, "logLevel", "info", "Log level (debug, info, warn or error)")
+	flag.StringVar(&tfLog, "tfLog", "", "File to write Terraform's TRACE log to, for debugging Terraform itself")
+	flag.StringVar(&logFormat, "logFormat", "text", "Log format (text or json)")
+	flag.BoolVar(&quiet, "quiet", false, "Only log warnings and errors (same as -logLevel warn)")
 	flag.BoolVar(&standalone, "standalone", false, "Generate standalone HTML files")
-	flag.BoolVar(&showSensitive, "showSensitive", false, "Display sensitive values")
+	flag.BoolVar(&serve, "serve", true, "Start the Rover server, set to false to exit after generating assets (when writing files, only if explicitly set)")
+	flag.Var(&anonymize, "anonymize", "Replace resource and module names with generic names, set to reversible to also generate a legend of the original names")
+	flag.BoolVar(&showSensitive, "showSensitive", false, "Display sensitive values instead of redacting them")
 	flag.BoolVar(&tfcNewRun, "tfcNewRun", false, "Create new Terraform Cloud run")
 	flag.BoolVar(&getVersion, "version", false, "Get current version")
+	flag.BoolVar(&check, "check", false, "Validate the flags, working directory and configuration, print the resolved configuration and exit without running init or plan")
 	flag.BoolVar(&genImage, "genImage", false, "Generate graph image")
-	flag.Var(&tfVarsFiles, "tfVarsFile", "Path to *.tfvars files")
-	flag.Var(&tfVars, "tfVar", "Terraform variable (key=value)")
-	flag.Var(&tfBackendConfigs, "tfBackendConfig", "Path to *.tfbackend files")
+	flag.BoolVar(&skipInit, "skipInit", false, "Skip terraform init")
+	flag.BoolVar(&upgrade, "upgrade", false, "Upgrade modules and providers during terraform init")
+	flag.BoolVar(&destroy, "destroy", false, "Visualize a destroy plan")
+	flag.BoolVar(&refresh, "refresh", true, "Refresh state during terraform plan, set to false to plan without querying providers")
+	flag.BoolVar(&fromState, "fromState", false, "Visualize the current state instead of a plan")
+	flag.DurationVar(&timeout, "timeout", 5*time.Minute, "Maximum duration of init, plan and show (0 disables the timeout)")
+	flag.IntVar(&parallelism, "parallelism", 10, "Number of concurrent operations during terraform plan, lower it to avoid provider rate limits")
+	flag.IntVar(&retries, "retries", 0, "Number of times to retry the plan if it fails with a transient error, like a state lock or network error")
+	flag.DurationVar(&lockTimeoutDuration, "lockTimeout", 0, "Duration to wait for a state lock during init and plan")
+	flag.BoolVar(&collapseInstances, "collapseInstances", false, "Collapse count and for_each instances under their resource in the map and graph")
+	flag.BoolVar(&hideNoOp, "hideNoOp", false, "Hide resources without changes from the map and graph")
+	flag.BoolVar(&enableMetrics, "metrics", false, "Serve Prometheus metrics at /metrics")
+	flag.BoolVar(&watch, "watch", false, "Regenerate assets when *.tf or *.tfvars files change")
+	flag.Var(tfVarFlag{&tfVars, true}, "tfVarsFile", "Path to *.tfvars files")
+	flag.Var(tfVarFlag{&tfVars, true}, "var-file", "Path to a *.tfvars file, like terraform plan -var-file (can be repeated)")
+	flag.Var(tfVarFlag{&tfVars, false}, "tfVar", "Terraform variable (key=value)")
+	flag.Var(tfVarFlag{&tfVars, false}, "var", "Terraform variable (key=value), like terraform plan -var, overriding earlier -var and -var-file (can be repeated)")
+	flag.Var(&tfBackendConfigs, "tfBackendConfig", "Path to *.tfbackend files or backend key=value settings (can be repeated)")
+	flag.Var(&tfBackendConfigs, "backendConfig", "Backend key=value setting or configuration file, like terraform init -backend-config (can be repeated)")
+	flag.Var(&envs, "env", "Environment variable (KEY=VALUE) to set for Terraform only, like credentials, TF_VAR_name or TF_LOG, overriding the inherited value (can be repeated)")
+	flag.Var(&targets, "target", "Resource address to target (can be repeated)")
+	flag.Var(&filters, "filter", "Only show resources matching the address or glob pattern and their neighbors (can be repeated)")
+	flag.Var(&includeTypes, "includeType", "Only show resources whose type matches the glob pattern, like aws_iam_* (can be repeated)")
+	flag.Var(&excludeTypes, "excludeType", "Hide resources whose type matches the glob pattern (can be repeated)")
 	flag.Parse()
 
 	if getVersion {
@@ -94,369 +224,727 @@ func main() {
 		return
 	}
 
-	log.Println("Starting Rover...")
+	if quiet {
+		if isFlagSet("logLevel") {
+			fatal(errors.New("-quiet and -logLevel can't be used together"))
+		}
+		logLevel = "warn"
+	}
+
+	err := setupLogging(logLevel, logFormat)
+	if err != nil {
+		fatal(err)
+	}
 
-	parsedTfVarsFiles := strings.Split(tfVarsFiles.String(), ",")
-	parsedTfVars := strings.Split(tfVars.String(), ",")
-	parsedTfBackendConfigs := strings.Split(tfBackendConfigs.String(), ",")
+	slog.Info("Starting Rover...")
 
-	path, err := os.Getwd()
+	addr, err := serverAddr(ipPort, bindAddr, port)
 	if err != nil {
-		log.Fatal(errors.New("Unable to get current working directory"))
+		fatal(err)
 	}
 
-	if planPath != "" {
-		if !strings.HasPrefix(planPath, "/") {
-			planPath = filepath.Join(path, planPath)
-		}
+	if maxDepth < 0 {
+		fatal(errors.New(fmt.Sprintf("Invalid maxDepth %d: must be 0 or greater", maxDepth)))
 	}
 
-	if planJSONPath != "" {
-		if !strings.HasPrefix(planJSONPath, "/") {
-			planJSONPath = filepath.Join(path, planJSONPath)
+	if collapseThreshold < 0 {
+		fatal(errors.New(fmt.Sprintf("Invalid collapseThreshold %d: must be 0 or greater", collapseThreshold)))
+	}
+
+	for name, d := range map[string]time.Duration{"readTimeout": readTimeout, "writeTimeout": writeTimeout, "idleTimeout": idleTimeout} {
+		if d < 0 {
+			fatal(errors.New(fmt.Sprintf("Invalid %s %s: must be 0 or greater", name, d)))
 		}
 	}
 
-	r := rover{
-		Name:             name,
-		WorkingDir:       workingDir,
-		TfPath:           tfPath,
-		PlanPath:         planPath,
-		PlanJSONPath:     planJSONPath,
-		ShowSensitive:    showSensitive,
-		GenImage:         genImage,
-		TfVarsFiles:      parsedTfVarsFiles,
-		TfVars:           parsedTfVars,
-		TfBackendConfigs: parsedTfBackendConfigs,
-		WorkspaceName:    workspaceName,
-		TFCOrgName:       tfcOrgName,
-		TFCWorkspaceName: tfcWorkspaceName,
-		TFCNewRun:        tfcNewRun,
+	if parallelism < 1 {
+		fatal(errors.New(fmt.Sprintf("Invalid parallelism %d: must be 1 or greater", parallelism)))
 	}
 
-	// Generate assets
-	err = r.generateAssets()
+	if retries < 0 {
+		fatal(errors.New(fmt.Sprintf("Invalid retries %d: must be 0 or greater", retries)))
+	}
+
+	err = checkTLS(tlsCert, tlsKey)
 	if err != nil {
-		log.Fatal(err.Error())
+		fatal(err)
 	}
 
-	log.Println("Done generating assets.")
+	if outputDir == "-" {
+		outputDir = ""
+		stdout = true
+	}
+	if stdout && serve && isFlagSet("serve") {
+		fatal(errors.New("-stdout can't be used with -serve"))
+	}
 
-	// Save to file (debug)
-	// saveJSONToFile(name, "plan", "output", r.Plan)
-	// saveJSONToFile(name, "rso", "output", r.Plan)
-	// saveJSONToFile(name, "map", "output", r.Map)
-	// saveJSONToFile(name, "graph", "output", r.Graph)
+	env, err := parseEnv(envs, tfLog != "")
+	if err != nil {
+		fatal(err)
+	}
 
-	// Embed frontend
-	fe, err := fs.Sub(frontend, "ui/dist")
+	failOnChanges, err := parseFailOn(failOn)
 	if err != nil {
-		log.Fatalln(err)
+		fatal(err)
 	}
-	frontendFS := http.FileServer(http.FS(fe))
 
-	if standalone {
-		err = r.generateZip(fe, fmt.Sprintf("%s.zip", zipFileName))
-		if err != nil {
-			log.Fatalln(err)
+	if tfcRunID != "" {
+		for _, f := range []string{"planPath", "planJSONPath", "tfcWorkspace", "tfcNewRun", "fromState"} {
+			if isFlagSet(f) {
+				fatal(errors.New(fmt.Sprintf("-%s can't be used with -tfcRunID", f)))
+			}
 		}
+	}
 
-		log.Printf("Generated zip file: %s.zip\n", zipFileName)
-		return
+	for _, p := range append(append([]string{}, includeTypes...), excludeTypes...) {
+		if _, err := path.Match(p, ""); err != nil {
+			fatal(errors.New(fmt.Sprintf("Invalid resource type pattern %q: %s", p, err)))
+		}
 	}
 
-	err = r.startServer(ipPort, frontendFS)
+	// When writing files, only start the server if explicitly requested
+	headless := !serve || stdout || ((outputDir != "" || graphOut != "" || mermaidOut != "" || svgOut != "" || exportHTML != "" || standalone) && !isFlagSet("serve"))
+	if len(failOnChanges) > 0 && !headless {
+		fatal(errors.New("-failOn requires headless mode, set -serve=false"))
+	}
+
+	basePath, err = normalizeBasePath(basePath)
 	if err != nil {
-		// http.Serve() returns error on shutdown
-		if genImage {
-			log.Println("Server shut down.")
-		} else {
-			log.Fatalf("Could not start server: %s\n", err.Error())
+		fatal(err)
+	}
+
+	if apiOnly {
+		for _, f := range []string{"uiDir", "genImage", "exportHTML", "standalone"} {
+			if isFlagSet(f) {
+				fatal(errors.New(fmt.Sprintf("-%s can't be used with -apiOnly", f)))
+			}
 		}
 	}
 
-}
+	if uiDir != "" {
+		if _, err := os.Stat(filepath.Join(uiDir, "index.html")); err != nil {
+			fatal(errors.New(fmt.Sprintf("Invalid uiDir (%s): no index.html found, build the frontend first", uiDir)))
+		}
+	}
 
-func (r *rover) generateAssets() error {
-	// Get Plan
-	err := r.getPlan()
-	if err != nil {
-		return errors.New(fmt.Sprintf("Unable to parse Plan: %s", err))
+	if tmpDir != "" {
+		if fi, err := os.Stat(tmpDir); err != nil || !fi.IsDir() {
+			fatal(errors.New(fmt.Sprintf("Invalid tmpDir (%s): must be an existing directory", tmpDir)))
+		}
 	}
 
-	// Generate RSO, Map, Graph
-	err = r.GenerateResourceOverview()
+	workingDirs, names, err = checkConfigs(workingDirs, names)
 	if err != nil {
-		return err
+		fatal(err)
 	}
 
-	err = r.GenerateMap()
-	if err != nil {
-		return err
+	// Multiple configurations can only be served, other flags apply to a single plan
+	if len(workingDirs) > 1 {
+		for _, f := range []string{"configDir", "stdout", "gitRepo", "planPath", "planJSONPath", "comparePlan", "tfcWorkspace", "tfcRunID", "outputDir", "graphOut", "mermaidOut", "svgOut", "exportHTML", "standalone", "genImage"} {
+			if isFlagSet(f) {
+				fatal(errors.New(fmt.Sprintf("-%s can't be used with multiple -workingDir", f)))
+			}
+		}
+		if !serve {
+			fatal(errors.New("Multiple -workingDir can only be served"))
+		}
 	}
 
-	err = r.GenerateGraph()
+	path, err := os.Getwd()
 	if err != nil {
-		return err
+		fatal(errors.New("Unable to get current working directory"))
 	}
 
-	return nil
-}
+	// Remove temporary files, like -gitRepo clones, when Rover returns
+	defer runCleanups()
 
-func (r *rover) getPlan() error {
-	tmpDir, err := ioutil.TempDir("", "rover")
-	if err != nil {
-		return err
+	if gitRepo != "" {
+		slog.Info("Cloning repository...", "repo", gitRepo, "ref", gitRef)
+
+		dir, cleanup, err := cloneRepo(context.Background(), gitRepo, gitRef, tmpDir)
+		if err != nil {
+			fatal(err)
+		}
+		atExit(cleanup)
+
+		workingDirs[0] = dir
+	} else if gitRef != "" {
+		fatal(errors.New("-gitRef requires -gitRepo"))
 	}
-	defer os.RemoveAll(tmpDir)
 
-	tf, err := tfexec.NewTerraform(r.WorkingDir, r.TfPath)
-	if err != nil {
-		return err
+	// Terraform runs in the root module, so the configuration is parsed and
+	// planned from the same directory
+	if configDir != "" {
+		workingDirs[0], err = joinConfigDir(workingDirs[0], configDir)
+		if err != nil {
+			fatal(err)
+		}
 	}
 
-	// If user provided path to plan file
-	if r.PlanPath != "" {
-		log.Println("Using provided plan...")
-		r.Plan, err = tf.ShowPlanFile(context.Background(), r.PlanPath)
+	for i, dir := range workingDirs {
+		workingDirs[i], err = normalizeDir(dir)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanPath, err))
+			fatal(err)
 		}
-		return nil
 	}
+	workingDir, name := workingDirs[0], names[0]
 
-	// If user provided path to plan JSON file
-	if r.PlanJSONPath != "" {
-		log.Println("Using provided JSON plan...")
+	if planPath != "" {
+		if !filepath.IsAbs(planPath) {
+			planPath = filepath.Join(path, planPath)
+		}
+	}
 
-		planJsonFile, err := os.Open(r.PlanJSONPath)
-		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanJSONPath, err))
+	if planJSONPath != "" {
+		if !filepath.IsAbs(planJSONPath) {
+			planJSONPath = filepath.Join(path, planJSONPath)
 		}
-		defer planJsonFile.Close()
+	}
 
-		planJson, err := ioutil.ReadAll(planJsonFile)
-		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanJSONPath, err))
+	// Terraform runs in the working directory, so the log path must be absolute
+	if tfLog != "" && !filepath.IsAbs(tfLog) {
+		tfLog = filepath.Join(path, tfLog)
+	}
+	if p := env["TF_LOG_PATH"]; p != "" && !filepath.IsAbs(p) {
+		env["TF_LOG_PATH"] = filepath.Join(path, p)
+	}
+
+	if comparePlanPath != "" {
+		if !filepath.IsAbs(comparePlanPath) {
+			comparePlanPath = filepath.Join(path, comparePlanPath)
 		}
+	}
 
-		if err := json.Unmarshal(planJson, &r.Plan); err != nil {
-			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanJSONPath, err))
+	var corsOrigins []string
+	for _, origin := range strings.Split(corsOrigin, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			corsOrigins = append(corsOrigins, origin)
 		}
+	}
 
-		return nil
+	r := app{
+		Rover: rover.New(rover.Config{
+			Name:              name,
+			WorkingDir:        workingDir,
+			TfPath:            tfPath,
+			PlanPath:          planPath,
+			PlanJSONPath:      planJSONPath,
+			ComparePlanPath:   comparePlanPath,
+			ShowSensitive:     showSensitive,
+			TfVars:            tfVars,
+			TfBackendConfigs:  tfBackendConfigs,
+			Targets:           targets,
+			Filters:           filters,
+			IncludeTypes:      includeTypes,
+			ExcludeTypes:      excludeTypes,
+			WorkspaceName:     workspaceName,
+			TFCOrgName:        tfcOrgName,
+			TFCWorkspaceName:  tfcWorkspaceName,
+			TFCRunID:          tfcRunID,
+			TFCToken:          tfcToken,
+			StrictVersion:     strictVersion,
+			TFCNewRun:         tfcNewRun,
+			SkipInit:          skipInit,
+			Upgrade:           upgrade,
+			Destroy:           destroy,
+			Refresh:           refresh,
+			FromState:         fromState,
+			CollapseInstances: collapseInstances,
+			HideNoOp:          hideNoOp,
+			MaxDepth:          maxDepth,
+			CollapseThreshold: collapseThreshold,
+			Anonymize:         anonymize != "",
+			AnonymizeLegend:   anonymize == "reversible",
+			Timeout:           timeout,
+			LockTimeout:       lockTimeoutDuration,
+			Retries:           retries,
+			Parallelism:       parallelism,
+			TmpDir:            tmpDir,
+			TfLogPath:         tfLog,
+			Env:               env,
+		}),
+		CorsOrigins:  corsOrigins,
+		AuthToken:    authToken,
+		BasePath:     basePath,
+		APIOnly:      apiOnly,
+		TLSCert:      tlsCert,
+		TLSKey:       tlsKey,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		IdleTimeout:  idleTimeout,
+		GenImage:     genImage,
+		Watch:        watch,
+	}
+	if enableMetrics {
+		r.metrics = newMetrics()
+	}
+
+	// Configurations after the first are served under /api/{name}/
+	configs := []*app{&r}
+	for i := 1; i < len(workingDirs); i++ {
+		c := r.clone()
+		c.Name = names[i]
+		c.WorkingDir = workingDirs[i]
+		configs = append(configs, c)
+	}
+
+	if check {
+		for _, c := range configs {
+			res, err := c.Check(context.Background())
+			if err != nil {
+				fatal(err)
+			}
+			printCheck(os.Stdout, c.Name, res)
+		}
+		return
 	}
 
-	// If user specified TFC workspace
-	if r.TFCWorkspaceName != "" {
-		tfcToken := os.Getenv("TFC_TOKEN")
+	// Generate assets
+	for _, c := range configs {
+		if len(configs) > 1 {
+			slog.Info("Generating assets...", "name", c.Name, "workingDir", c.WorkingDir)
+		}
 
-		if tfcToken == "" {
-			return errors.New("TFC_TOKEN environment variable not set")
+		err = c.generateAssets(context.Background())
+		if err != nil {
+			slog.Error(err.Error())
+			// Distinguish plan failures from configuration failures
+			if errors.As(err, &rover.PlanError{}) {
+				exit(exitPlanError)
+			}
+			exit(exitConfigError)
 		}
+	}
+
+	slog.Info("Done generating assets.")
+
+	// CI runs don't need artifacts for plans without changes
+	skipEmpty := headless && r.emptyPlan() && !emitEmpty
+	if skipEmpty {
+		slog.Info("Not writing assets for a plan without changes, set -emitEmpty to write them")
+		outputDir, graphOut, mermaidOut, svgOut, exportHTML = "", "", "", "", ""
+		standalone = false
+	}
 
-		if r.TFCOrgName == "" {
-			return errors.New("Must specify Terraform Cloud organization to retrieve plan from Terraform Cloud")
+	if outputDir != "" {
+		err = r.WriteAssets(outputDir)
+		if err != nil {
+			fatal(err)
 		}
+	}
 
-		config := &tfe.Config{
-			Token: tfcToken,
+	// Logs go to stderr, so stdout only has the assets
+	if stdout && !skipEmpty {
+		err = r.WriteAssetsJSON(os.Stdout)
+		if err != nil {
+			fatal(errors.New(fmt.Sprintf("Unable to write assets to stdout: %s", err)))
 		}
+	}
 
-		client, err := tfe.NewClient(config)
+	if graphOut != "" {
+		err = os.WriteFile(graphOut, r.GenerateDOT(), 0644)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to connect to Terraform Cloud. %s", err))
+			fatal(errors.New(fmt.Sprintf("Unable to write graph (%s): %s", graphOut, err)))
 		}
+		slog.Info("Wrote graph", "file", graphOut)
+	}
 
-		// Get TFC Workspace
-		ws, err := client.Workspaces.Read(context.Background(), r.TFCOrgName, r.TFCWorkspaceName)
+	if mermaidOut != "" {
+		err = os.WriteFile(mermaidOut, r.GenerateMermaid(), 0644)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to list workspace %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+			fatal(errors.New(fmt.Sprintf("Unable to write Mermaid diagram (%s): %s", mermaidOut, err)))
 		}
+		slog.Info("Wrote Mermaid diagram", "file", mermaidOut)
+	}
 
-		// Retrieve all runs from specified TFC workspace
-		runs, err := client.Runs.List(context.Background(), ws.ID, tfe.RunListOptions{})
+	if svgOut != "" {
+		err = os.WriteFile(svgOut, r.GenerateSVG(), 0644)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to retrieve plan from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+			fatal(errors.New(fmt.Sprintf("Unable to write SVG image (%s): %s", svgOut, err)))
 		}
+		slog.Info("Wrote SVG image", "file", svgOut)
+	}
 
-		run := runs.Items[0]
+	// Embed frontend
+	fe, err := fs.Sub(frontend, "ui/dist")
+	if err != nil {
+		fatal(err)
+	}
+	var frontendFS http.Handler = http.FileServer(http.FS(fe))
 
-		// Get most recent plan item
-		planID := runs.Items[0].Plan.ID
+	// Serve the frontend from disk so UI changes don't require a rebuild
+	if uiDir != "" {
+		fe = os.DirFS(uiDir)
+		frontendFS = http.FileServer(http.Dir(uiDir))
+	}
+	if basePath != "" {
+		frontendFS = basePathFrontend(fe, basePath, frontendFS)
+	}
+	frontendFS = spaHandler(fe, frontendFS)
 
-		// Run hasn't been applied or discarded, therefore is still "actionable" by user
-		runIsActionable := run.StatusTimestamps.AppliedAt.IsZero() && run.StatusTimestamps.DiscardedAt.IsZero()
+	if exportHTML != "" {
+		err = r.generateHTML(fe, exportHTML)
+		if err != nil {
+			fatal(errors.New(fmt.Sprintf("Unable to write HTML file (%s): %s", exportHTML, err)))
+		}
+		slog.Info("Wrote HTML file", "file", exportHTML)
+	}
 
-		if runIsActionable && r.TFCNewRun {
-			return errors.New(fmt.Sprintf("Did not create new run. %s in %s in %s is still active", run.ID, r.TFCWorkspaceName, r.TFCOrgName))
+	if standalone {
+		err = r.generateZip(fe, fmt.Sprintf("%s.zip", zipFileName))
+		if err != nil {
+			fatal(err)
 		}
 
-		// If latest run is not actionable, rover will create new run
-		if r.TFCNewRun {
-			// Create new run in specified TFC workspace
-			newRun, err := client.Runs.Create(context.Background(), tfe.RunCreateOptions{
-				Refresh:   &TRUE,
-				Workspace: ws,
-			})
-			if err != nil {
-				return errors.New(fmt.Sprintf("Unable to generate new run from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
-			}
+		slog.Info("Generated zip file", "file", fmt.Sprintf("%s.zip", zipFileName))
+	}
 
-			run = newRun
+	if summaryOut != "" {
+		err = os.WriteFile(summaryOut, newPlanSummary(r.Rover).JSON(), 0644)
+		if err != nil {
+			fatal(errors.New(fmt.Sprintf("Unable to write summary (%s): %s", summaryOut, err)))
+		}
+		slog.Info("Wrote summary", "file", summaryOut)
+	}
 
-			log.Printf("Starting new Terraform Cloud run in %s workspace...", r.TFCWorkspaceName)
+	if headless {
+		summary := newPlanSummary(r.Rover)
+		if !stdout {
+			os.Stdout.Write(summary.JSON())
+		}
 
-			// Wait maximum of 5 mins
-			for i := 0; i < 30; i++ {
-				run, err := client.Runs.Read(context.Background(), newRun.ID)
-				if err != nil {
-					return errors.New(fmt.Sprintf("Unable to retrieve run from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
-				}
+		if change, ok := summary.failed(failOnChanges); ok {
+			slog.Error(fmt.Sprintf("Plan has changes matching -failOn: %s", change))
+			exit(exitFailOn)
+		}
+		return
+	}
 
-				if run.Plan != nil {
-					planID = run.Plan.ID
-					// Add 20 second timeout so plan JSON becomes available
-					time.Sleep(20 * time.Second)
-					log.Printf("Run %s to completed!", newRun.ID)
-					break
-				}
+	// -standalone only writes the zip
+	if standalone {
+		return
+	}
 
-				time.Sleep(10 * time.Second)
-				log.Printf("Waiting for run %s to complete (%ds)...", newRun.ID, 10*(i+1))
-			}
+	if len(configs) == 1 {
+		configs = nil
+	}
 
-			if planID == "" {
-				return errors.New(fmt.Sprintf("Timeout waiting for plan to complete in %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
-			}
+	err = r.startServer(addr, frontendFS, configs)
+	if err != nil {
+		fatal(err)
+	}
+
+	if genImage {
+		slog.Info("Server shut down.")
+	}
+}
+
+// isFlagSet reports whether the flag was passed on the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
+	})
+	return set
+}
 
-		// Get most recent plan file
-		planBytes, err := client.Plans.JSONOutput(context.Background(), planID)
+// serverAddr returns the address the server listens on, validating the port.
+// ipPort takes precedence over bindAddr and port when set.
+func serverAddr(ipPort string, bindAddr string, port int) (string, error) {
+	if ipPort != "" {
+		host, p, err := net.SplitHostPort(ipPort)
 		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to retrieve plan from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+			return "", errors.New(fmt.Sprintf("Invalid ipPort (%s): %s", ipPort, err))
 		}
-		// If empty plan file
-		if string(planBytes) == "" {
-			return errors.New(fmt.Sprintf("Empty plan. Check run %s in %s in %s is not pending", run.ID, r.TFCWorkspaceName, r.TFCOrgName))
+		port, err = strconv.Atoi(p)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Invalid port in ipPort (%s): %s", ipPort, err))
 		}
-
-		if err := json.Unmarshal(planBytes, &r.Plan); err != nil {
-			return errors.New(fmt.Sprintf("Unable to parse plan (ID: %s) from %s in %s organization.: %s", planID, r.TFCWorkspaceName, r.TFCOrgName, err))
+		if err := validatePort(port); err != nil {
+			return "", err
 		}
+		return net.JoinHostPort(host, p), nil
+	}
 
-		return nil
+	if err := validatePort(port); err != nil {
+		return "", err
 	}
 
-	log.Println("Initializing Terraform...")
+	// JoinHostPort brackets IPv6 literals such as ::1
+	return net.JoinHostPort(bindAddr, strconv.Itoa(port)), nil
+}
 
-	// Create TF Init options
-	var tfInitOptions []tfexec.InitOption
-	tfInitOptions = append(tfInitOptions, tfexec.Upgrade(true))
+// printCheck prints the configuration -check resolved
+func printCheck(w io.Writer, name string, res *rover.CheckResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
 
-	// Add *.tfbackend files
-	for _, tfBackendConfig := range r.TfBackendConfigs {
-		if tfBackendConfig != "" {
-			tfInitOptions = append(tfInitOptions, tfexec.BackendConfig(tfBackendConfig))
+	fmt.Fprintf(tw, "Configuration:\t%s\n", name)
+	fmt.Fprintf(tw, "Source:\t%s\n", res.Source)
+	if res.PlanPath != "" {
+		fmt.Fprintf(tw, "Plan:\t%s\n", res.PlanPath)
+	}
+	if res.TfPath != "" {
+		fmt.Fprintf(tw, "Working directory:\t%s\n", res.WorkingDir)
+		fmt.Fprintf(tw, "%s:\t%s (v%s)\n", res.Product, res.TfPath, res.TerraformVersion)
+	}
+	if res.Workspace != "" {
+		workspace := res.Workspace
+		if res.CreateWorkspace {
+			workspace += " (will be created)"
 		}
+		fmt.Fprintf(tw, "Workspace:\t%s\n", workspace)
 	}
+	if res.Source == "plan" || res.Source == "state" {
+		fmt.Fprintf(tw, "Resources:\t%d\n", res.Resources)
+		fmt.Fprintf(tw, "Module calls:\t%d\n", res.Modules)
+	}
+	fmt.Fprintf(tw, "Var files:\t%d\n", res.VarFiles)
+	fmt.Fprintf(tw, "Vars:\t%d\n", res.Vars)
+	fmt.Fprintf(tw, "Backend configs:\t%d\n", res.BackendConfigs)
+	fmt.Fprintf(tw, "Targets:\t%d\n", res.Targets)
+}
 
-	// tfInitOptions = append(tfInitOptions, tfexec.LockTimeout("60s"))
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// reservedNames are API paths that configuration names would shadow
+var reservedNames = map[string]bool{
+	"configs":      true,
+	"dependencies": true,
+	"dependents":   true,
+	"events":       true,
+	"health":       true,
+	"ready":        true,
+	"regenerate":   true,
+	"schema":       true,
+	"search":       true,
+	"version":      true,
+}
 
-	err = tf.Init(context.Background(), tfInitOptions...)
-	if err != nil {
-		return errors.New(fmt.Sprintf("Unable to initialize Terraform Plan: %s", err))
+// checkConfigs defaults workingDirs and names and checks there's a unique name
+// for each working directory when there are multiple
+func checkConfigs(workingDirs []string, names []string) ([]string, []string, error) {
+	if len(workingDirs) == 0 {
+		workingDirs = []string{"."}
+	}
+	if len(names) == 0 && len(workingDirs) == 1 {
+		names = []string{"rover"}
 	}
 
-	if r.WorkspaceName != "" {
-		log.Printf("Running in %s workspace...", r.WorkspaceName)
-		err = tf.WorkspaceSelect(context.Background(), r.WorkspaceName)
-		if err != nil {
-			return errors.New(fmt.Sprintf("Unable to select workspace (%s): %s", r.WorkspaceName, err))
+	// Names are used in API paths and file names
+	for _, n := range names {
+		if !validName.MatchString(n) {
+			return nil, nil, errors.New(fmt.Sprintf("Invalid name %q: must only contain letters, digits, - and _", n))
 		}
 	}
 
-	log.Println("Generating plan...")
-	planPath := fmt.Sprintf("%s/%s-%v", tmpDir, "roverplan", time.Now().Unix())
+	if len(workingDirs) == 1 {
+		if len(names) > 1 {
+			return nil, nil, errors.New("Multiple -name require a -workingDir for each")
+		}
+		return workingDirs, names, nil
+	}
 
-	// Create TF Plan options
-	var tfPlanOptions []tfexec.PlanOption
-	tfPlanOptions = append(tfPlanOptions, tfexec.Out(planPath))
+	if len(names) != len(workingDirs) {
+		return nil, nil, errors.New(fmt.Sprintf("Each -workingDir requires a -name: got %d working directories and %d names", len(workingDirs), len(names)))
+	}
 
-	// Add *.tfvars files
-	for _, tfVarsFile := range r.TfVarsFiles {
-		if tfVarsFile != "" {
-			tfPlanOptions = append(tfPlanOptions, tfexec.VarFile(tfVarsFile))
+	seen := make(map[string]bool)
+	for _, n := range names {
+		if reservedNames[n] {
+			return nil, nil, errors.New(fmt.Sprintf("Invalid name %q: reserved for the API", n))
+		}
+		if seen[n] {
+			return nil, nil, errors.New(fmt.Sprintf("Duplicate name %q: each -workingDir requires a unique -name", n))
 		}
+		seen[n] = true
 	}
 
-	// Add Terraform variables
-	for _, tfVar := range r.TfVars {
-		if tfVar != "" {
-			tfPlanOptions = append(tfPlanOptions, tfexec.Var(tfVar))
+	return workingDirs, names, nil
+}
+
+// normalizeDir expands a leading ~ in the working directory and makes it
+// absolute, so Terraform and the configuration loader resolve it the same way
+func normalizeDir(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Unable to expand working directory %s: %s", dir, err))
 		}
+		dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
 	}
 
-	_, err = tf.Plan(context.Background(), tfPlanOptions...)
+	dir, err := filepath.Abs(dir)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Unable to run Plan: %s", err))
+		return "", errors.New(fmt.Sprintf("Invalid working directory %s: %s", dir, err))
 	}
 
-	r.Plan, err = tf.ShowPlanFile(context.Background(), planPath)
+	fi, err := os.Stat(dir)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Unable to read Plan: %s", err))
+		if os.IsNotExist(err) {
+			return "", errors.New(fmt.Sprintf("Working directory %s doesn't exist", dir))
+		}
+		return "", errors.New(fmt.Sprintf("Unable to read working directory %s: %s", dir, err))
+	}
+	if !fi.IsDir() {
+		return "", errors.New(fmt.Sprintf("Working directory %s is not a directory", dir))
 	}
 
-	return nil
+	return dir, nil
 }
 
-func showJSON(g interface{}) {
-	j, err := json.Marshal(g)
-	if err != nil {
-		log.Printf("Error producing JSON: %s\n", err)
-		os.Exit(2)
+// joinConfigDir returns the root module directory configDir within workingDir
+func joinConfigDir(workingDir string, configDir string) (string, error) {
+	if filepath.IsAbs(configDir) {
+		return "", errors.New(fmt.Sprintf("Invalid configDir (%s): must be relative to the working directory", configDir))
+	}
+
+	dir := filepath.Join(workingDir, configDir)
+	if rel, err := filepath.Rel(workingDir, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New(fmt.Sprintf("Invalid configDir (%s): must be inside the working directory", configDir))
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", errors.New(fmt.Sprintf("Invalid configDir (%s): %s is not a directory", configDir, dir))
 	}
-	log.Printf("%+v", string(j))
+
+	return dir, nil
 }
 
-func showModuleJSON(module *tfconfig.Module) {
-	j, err := json.MarshalIndent(module, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error producing JSON: %s\n", err)
-		os.Exit(2)
+// parseEnv parses the -env KEY=VALUE pairs, a later pair overriding an
+// earlier one for the same key. tfexec sets most of Terraform's own
+// variables, of those only TF_LOG, TF_LOG_PATH and TF_VAR_name can be set.
+func parseEnv(envs []string, tfLog bool) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, e := range envs {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, errors.New(fmt.Sprintf("Invalid env %q: must be KEY=VALUE", e))
+		}
+		env[k] = v
+	}
+
+	prohibited := tfexec.ProhibitedEnv(env)
+	sort.Strings(prohibited)
+	for _, k := range prohibited {
+		switch {
+		case k == "TF_LOG" || k == "TF_LOG_PATH" || strings.HasPrefix(k, "TF_VAR_"):
+		case k == "TF_WORKSPACE":
+			return nil, errors.New("Invalid env TF_WORKSPACE: use -workspaceName to select a workspace")
+		default:
+			return nil, errors.New(fmt.Sprintf("Invalid env %s: set by Rover's Terraform wrapper", k))
+		}
+	}
+
+	// Terraform's log is written to a file so it doesn't mix with its output
+	level := env["TF_LOG"]
+	if level != "" && !strings.EqualFold(level, "off") && !tfLog && env["TF_LOG_PATH"] == "" && os.Getenv("TF_LOG_PATH") == "" {
+		return nil, errors.New("Invalid env TF_LOG: requires TF_LOG_PATH or -tfLog")
+	}
+
+	return env, nil
+}
+
+// normalizeBasePath returns basePath with a leading slash and without a
+// trailing slash, or an empty string to serve Rover at the root
+func normalizeBasePath(basePath string) (string, error) {
+	if strings.ContainsAny(basePath, "?#") {
+		return "", errors.New(fmt.Sprintf("Invalid basePath (%s): must be a path", basePath))
 	}
-	os.Stdout.Write(j)
-	os.Stdout.Write([]byte{'\n'})
+
+	return strings.TrimSuffix(path.Clean("/"+basePath), "/"), nil
 }
 
-func saveJSONToFile(prefix string, fileType string, path string, j interface{}) string {
-	b, err := json.Marshal(j)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error producing JSON: %s\n", err)
-		os.Exit(2)
+// checkTLS validates that the certificate and key are set together and load
+func checkTLS(tlsCert string, tlsKey string) error {
+	if tlsCert == "" && tlsKey == "" {
+		return nil
+	}
+	if tlsCert == "" || tlsKey == "" {
+		return errors.New("Both -tlsCert and -tlsKey are required to serve Rover over HTTPS")
 	}
 
-	newpath := filepath.Join(".", fmt.Sprintf("%s/%s", path, prefix))
-	err = os.MkdirAll(newpath, os.ModePerm)
+	_, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
 	if err != nil {
-		log.Fatal(err)
+		return errors.New(fmt.Sprintf("Unable to load TLS certificate (%s) and key (%s): %s", tlsCert, tlsKey, err))
 	}
 
-	f, err := os.Create(fmt.Sprintf("%s/%s-%s.json", newpath, prefix, fileType))
+	return nil
+}
+
+func validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return errors.New(fmt.Sprintf("Invalid port %d: must be between 1 and 65535", port))
+	}
+	return nil
+}
+
+// clone returns a copy of r's configuration without the generated assets
+func (r *app) clone() *app {
+	c := *r
+	c.Rover = rover.New(r.Config)
+	c.Ready = false
+	c.cache = nil
+	c.hiddenCache = nil
+	c.changedPlan = nil
+	c.etag = ""
+	return &c
+}
+
+func (r *app) generateAssets(ctx context.Context) (err error) {
+	if r.metrics != nil {
+		start := time.Now()
+		defer func() { r.metrics.observeGeneration(start, err) }()
+	}
+
+	err = r.Generate(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	defer f.Close()
+	hidden := r.WithoutNoOp()
 
-	_, err = f.WriteString(string(b))
+	err = r.cacheAssets(hidden)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	return fmt.Sprintf("%s/%s-%s.json", newpath, prefix, fileType)
+	// Files are written without no-op resources
+	if r.HideNoOp {
+		r.Graph = hidden.Graph
+		r.Map = hidden.Map
+	}
+
+	if r.emptyPlan() {
+		slog.Info("No changes detected", "name", r.Name)
+	}
+
+	r.Ready = true
+
+	return nil
+}
+
+// emptyPlan reports whether the generated plan doesn't change any resources
+func (r *app) emptyPlan() bool {
+	return r.Meta != nil && r.Meta.HasChanges != nil && !*r.Meta.HasChanges
 }
 
-func enableCors(w *http.ResponseWriter) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
+// enableCors sets the CORS headers if the request's Origin is in r.CorsOrigins
+func (r *app) enableCors(w http.ResponseWriter, req *http.Request) {
+	if len(r.CorsOrigins) == 0 {
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	origin := req.Header.Get("Origin")
+	for _, allowed := range r.CorsOrigins {
+		if allowed == "*" || allowed == origin {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Rover-API-Version")
+			return
+		}
+	}
 }
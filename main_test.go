@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rover/pkg/rover"
+)
+
+func TestJoinConfigDir(t *testing.T) {
+	workingDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workingDir, "infra", "prod"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workingDir, "README.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		configDir string
+		want      string
+		err       string
+	}{
+		{configDir: "infra", want: filepath.Join(workingDir, "infra")},
+		{configDir: "infra/prod", want: filepath.Join(workingDir, "infra", "prod")},
+		{configDir: "./infra/../infra/prod/", want: filepath.Join(workingDir, "infra", "prod")},
+		{configDir: ".", want: workingDir},
+		{configDir: filepath.Join(workingDir, "infra"), err: "must be relative to the working directory"},
+		{configDir: "..", err: "must be inside the working directory"},
+		{configDir: "infra/../../other", err: "must be inside the working directory"},
+		{configDir: "missing", err: "is not a directory"},
+		{configDir: "README.md", err: "is not a directory"},
+	}
+
+	for _, tt := range tests {
+		got, err := joinConfigDir(workingDir, tt.configDir)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("joinConfigDir(%q) error = %v, want %q", tt.configDir, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("joinConfigDir(%q) error = %s", tt.configDir, err)
+		} else if got != tt.want {
+			t.Errorf("joinConfigDir(%q) = %s, want %s", tt.configDir, got, tt.want)
+		}
+	}
+}
+
+// TestConfigDirMismatch checks that with a -configDir below the working
+// directory, the configuration is parsed from the root module rather than
+// from the directory Rover was run in
+func TestConfigDirMismatch(t *testing.T) {
+	workingDir := t.TempDir()
+	configDir := filepath.Join(workingDir, "infra")
+	if err := os.Mkdir(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A configuration in the working directory that isn't the root module
+	if err := os.WriteFile(filepath.Join(workingDir, "other.tf"), []byte("resource \"null_resource\" \"other\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "main.tf"), []byte("resource \"random_pet\" \"web\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := joinConfigDir(workingDir, "infra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rover.New(rover.Config{
+		Name:         "rover",
+		WorkingDir:   dir,
+		PlanJSONPath: filepath.Join("testdata", "plan.json"),
+	})
+	if err := r.Generate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	module := r.RSO.Configs[""].Module
+	if module == nil {
+		t.Fatal("root module configuration wasn't loaded")
+	}
+	if module.Path != configDir {
+		t.Errorf("root module loaded from %s, want %s", module.Path, configDir)
+	}
+	if _, ok := module.ManagedResources["random_pet.web"]; !ok {
+		t.Errorf("root module is missing random_pet.web from %s", configDir)
+	}
+	if _, ok := module.ManagedResources["null_resource.other"]; ok {
+		t.Errorf("root module has null_resource.other from the working directory %s", workingDir)
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	t.Setenv("TF_LOG_PATH", "")
+
+	tests := []struct {
+		envs  []string
+		tfLog bool
+		want  map[string]string
+		err   string
+	}{
+		{
+			envs: []string{"AWS_PROFILE=staging", "TF_VAR_region=us-east-1", "AWS_PROFILE=prod", "EMPTY="},
+			want: map[string]string{"AWS_PROFILE": "prod", "TF_VAR_region": "us-east-1", "EMPTY": ""},
+		},
+		{
+			envs: []string{"TF_LOG=DEBUG", "TF_LOG_PATH=terraform.log"},
+			want: map[string]string{"TF_LOG": "DEBUG", "TF_LOG_PATH": "terraform.log"},
+		},
+		{envs: []string{"TF_LOG=DEBUG"}, tfLog: true, want: map[string]string{"TF_LOG": "DEBUG"}},
+		{envs: []string{"TF_LOG=off"}, want: map[string]string{"TF_LOG": "off"}},
+		{envs: []string{"TF_LOG=DEBUG"}, err: "requires TF_LOG_PATH or -tfLog"},
+		{envs: []string{"TF_WORKSPACE=prod"}, err: "use -workspaceName"},
+		{envs: []string{"TF_CLI_ARGS_plan=-refresh=false"}, err: "set by Rover's Terraform wrapper"},
+		{envs: []string{"TF_IN_AUTOMATION=1"}, err: "set by Rover's Terraform wrapper"},
+		{envs: []string{"AWS_PROFILE"}, err: "must be KEY=VALUE"},
+		{envs: []string{"=value"}, err: "must be KEY=VALUE"},
+		{envs: []string{"AWS PROFILE=prod"}, err: "must be KEY=VALUE"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseEnv(tt.envs, tt.tfLog)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseEnv(%q) error = %v, want %q", tt.envs, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseEnv(%q) error = %s", tt.envs, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseEnv(%q) = %v, want %v", tt.envs, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("parseEnv(%q)[%s] = %q, want %q", tt.envs, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestTfVarFlag(t *testing.T) {
+	var tfVars []rover.TfVar
+	fs := flag.NewFlagSet("rover", flag.ContinueOnError)
+	fs.Var(tfVarFlag{&tfVars, true}, "tfVarsFile", "")
+	fs.Var(tfVarFlag{&tfVars, true}, "var-file", "")
+	fs.Var(tfVarFlag{&tfVars, false}, "tfVar", "")
+	fs.Var(tfVarFlag{&tfVars, false}, "var", "")
+
+	err := fs.Parse([]string{"-var-file", "base.tfvars", "-var", "size=2", "-tfVarsFile", "prod.tfvars", "-tfVar", "size=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []rover.TfVar{
+		{Value: "base.tfvars", File: true},
+		{Value: "size=2"},
+		{Value: "prod.tfvars", File: true},
+		{Value: "size=3"},
+	}
+	if !reflect.DeepEqual(tfVars, want) {
+		t.Errorf("tfVars = %v, want %v", tfVars, want)
+	}
+}
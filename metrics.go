@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are Rover's Prometheus metrics, served at /metrics with -metrics
+type metrics struct {
+	registry *prometheus.Registry
+
+	generations        *prometheus.CounterVec
+	generationDuration prometheus.Histogram
+	lastGeneration     prometheus.Gauge
+	lastSuccess        prometheus.Gauge
+	requests           *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		generations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rover_generations_total",
+			Help: "Number of asset generations by result.",
+		}, []string{"result"}),
+		generationDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "rover_generation_duration_seconds",
+			Help:    "Duration of asset generations, including terraform init and plan.",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+		}),
+		lastGeneration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rover_last_generation_timestamp_seconds",
+			Help: "Time of the last asset generation.",
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rover_last_generation_success",
+			Help: "Whether the last asset generation succeeded (1) or failed (0).",
+		}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rover_http_requests_total",
+			Help: "Number of HTTP requests by endpoint and status code.",
+		}, []string{"endpoint", "code"}),
+	}
+
+	m.registry.MustRegister(
+		m.generations,
+		m.generationDuration,
+		m.lastGeneration,
+		m.lastSuccess,
+		m.requests,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// observeGeneration records an asset generation that started at start
+func (m *metrics) observeGeneration(start time.Time, err error) {
+	m.generationDuration.Observe(time.Since(start).Seconds())
+	m.lastGeneration.SetToCurrentTime()
+
+	if err != nil {
+		m.generations.WithLabelValues("failure").Inc()
+		m.lastSuccess.Set(0)
+		return
+	}
+	m.generations.WithLabelValues("success").Inc()
+	m.lastSuccess.Set(1)
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// requestsHandler counts requests by endpoint and status code
+func (m *metrics) requestsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, r)
+		m.requests.WithLabelValues(metricsEndpoint(r.URL.Path), strconv.Itoa(sw.status)).Inc()
+	})
+}
+
+// metricsAPIEndpoints are the API paths labeled individually
+var metricsAPIEndpoints = map[string]bool{
+	"/api/health":       true,
+	"/api/ready":        true,
+	"/api/version":      true,
+	"/api/events":       true,
+	"/api/search":       true,
+	"/api/regenerate":   true,
+	"/api/dependents":   true,
+	"/api/dependencies": true,
+	"/api/plan":         true,
+	"/api/rso":          true,
+	"/api/map":          true,
+	"/api/graph":        true,
+	"/api/meta":         true,
+	"/api/providers":    true,
+	"/api/diff":         true,
+	"/api/legend":       true,
+	"/api/graph.dot":    true,
+	"/api/graph.mmd":    true,
+	"/api/graph.svg":    true,
+}
+
+// metricsEndpoint returns the endpoint label for path. Frontend files share
+// a label so the number of label values stays bounded.
+func metricsEndpoint(path string) string {
+	switch {
+	case path == "/health" || path == "/metrics":
+		return path
+	case strings.HasPrefix(path, "/api/schema/"):
+		return "/api/schema"
+	case metricsAPIEndpoints[path]:
+		return path
+	case strings.HasPrefix(path, "/api/"):
+		return "/api/other"
+	default:
+		return "frontend"
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Responses smaller than this are sent uncompressed
+const gzipMinSize = 1024
+
+// gzipResponseWriter buffers the response until it reaches gzipMinSize,
+// then compresses the rest of it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status int
+	buf    []byte
+	gz     *gzip.Writer
+	// plain is set when the response is passed through uncompressed
+	plain bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.status != 0 {
+		return
+	}
+	g.status = status
+
+	// Only compress successful responses that aren't already encoded or streamed
+	h := g.Header()
+	if status != http.StatusOK || h.Get("Content-Encoding") != "" || h.Get("Content-Type") == "text/event-stream" {
+		g.plain = true
+		g.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.status == 0 {
+		g.WriteHeader(http.StatusOK)
+	}
+
+	if g.plain {
+		return g.ResponseWriter.Write(b)
+	}
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= gzipMinSize {
+		if err := g.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+func (g *gzipResponseWriter) startGzip() error {
+	h := g.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.ResponseWriter.WriteHeader(g.status)
+
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	_, err := g.gz.Write(g.buf)
+	g.buf = nil
+
+	return err
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if g.gz == nil && !g.plain && g.status != 0 {
+		g.startGzip()
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// Close finishes the response, sending it uncompressed if it's below gzipMinSize
+func (g *gzipResponseWriter) Close() error {
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	if g.plain || g.status == 0 {
+		return nil
+	}
+
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(g.buf)
+
+	return err
+}
+
+// gzipHandler compresses responses for clients that accept gzip
+func gzipHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if r.Method == http.MethodHead || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+
+		h.ServeHTTP(gw, r)
+	})
+}
+
+// apiVersionHandler adds the API version to API responses so clients can detect
+// incompatible changes
+func apiVersionHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			w.Header().Set("X-Rover-API-Version", API_VERSION)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// spaHandler serves index.html for client-side routes that don't exist in fe,
+// so deep links and refreshes work. Missing files with an extension, like
+// scripts and stylesheets, still return 404.
+func spaHandler(fe fs.FS, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name == "" || strings.HasPrefix(r.URL.Path, "/api/") || path.Ext(name) != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if _, err := fs.Stat(fe, name); err == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/"
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// basePathHandler serves h under basePath, like /rover, redirecting basePath
+// itself to basePath/ so the frontend's relative URLs resolve
+func basePathHandler(basePath string, h http.Handler) http.Handler {
+	strip := http.StripPrefix(basePath, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath {
+			u := *r.URL
+			u.Path = basePath + "/"
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, basePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		strip.ServeHTTP(w, r)
+	})
+}
+
+var (
+	// Root-relative href and src attributes in index.html
+	rootURLAttr = regexp.MustCompile(`(href|src)="/([^/"][^"]*)?"`)
+	// API requests and the public path in the built frontend's scripts
+	rootAPIURL = regexp.MustCompile(`"/api/`)
+	publicPath = regexp.MustCompile(`\b([A-Za-z_$]+)\.p="/"`)
+)
+
+// basePathFrontend serves the frontend with a base element for basePath and
+// relative asset and API URLs, so it works behind a proxy at basePath
+func basePathFrontend(fe fs.FS, basePath string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name == "" {
+			name = "index.html"
+		}
+
+		var rewrite func(string) string
+		switch {
+		case name == "index.html":
+			rewrite = func(s string) string {
+				s = rootURLAttr.ReplaceAllString(s, `$1="$2"`)
+				return strings.Replace(s, "<head>", fmt.Sprintf(`<head><base href="%s/">`, html.EscapeString(basePath)), 1)
+			}
+		case path.Ext(name) == ".js":
+			rewrite = func(s string) string {
+				s = rootAPIURL.ReplaceAllString(s, `"api/`)
+				return publicPath.ReplaceAllString(s, `$1.p=""`)
+			}
+		default:
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		b, err := fs.ReadFile(fe, name)
+		if err != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+		w.Header().Set("Cache-Control", "no-cache")
+		io.WriteString(w, rewrite(string(b)))
+	})
+}
+
+// statusWriter records the response status code and size
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush supports streaming responses like /api/events
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// logHandler logs each request at debug level
+func logHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slog.Default().Enabled(r.Context(), slog.LevelDebug) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, r)
+
+		slog.Debug("Request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"bytes", sw.bytes,
+			"duration", time.Since(start),
+		)
+	})
+}
+
+// Cookie set after authenticating with ?token= so the frontend's API requests are authorized
+const authCookie = "rover_token"
+
+// authHandler requires token as a bearer token, token query parameter or cookie.
+// Health checks and CORS preflight requests are allowed without it.
+func authHandler(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health", "/api/health", "/api/ready":
+			h.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == http.MethodOptions {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if q := r.URL.Query().Get("token"); q != "" && tokenMatches(q, token) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     authCookie,
+				Value:    q,
+				Path:     "/",
+				HttpOnly: true,
+				// Only sent back over HTTPS if it was set over HTTPS
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") && tokenMatches(strings.TrimPrefix(bearer, "Bearer "), token) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if c, err := r.Cookie(authCookie); err == nil && tokenMatches(c.Value, token) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="rover"`)
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+	})
+}
+
+// tokenMatches compares tokens in constant time
+func tokenMatches(given string, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
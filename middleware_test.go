@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthCookie(t *testing.T) {
+	h := authHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, https := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodGet, "/?token=secret", nil)
+		if https {
+			req.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != authCookie {
+			t.Fatalf("https %t: cookies = %v, want %s", https, cookies, authCookie)
+		}
+		c := cookies[0]
+		if c.Secure != https {
+			t.Errorf("https %t: Secure = %t", https, c.Secure)
+		}
+		if !c.HttpOnly || c.SameSite != http.SameSiteStrictMode {
+			t.Errorf("https %t: cookie %s isn't HttpOnly and SameSite=Strict", https, c)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package rover
+
+import (
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Complexity measures the size and shape of the graph, to track growth
+// between plans
+type Complexity struct {
+	// Resources and data sources, counting those with count or for_each once
+	Resources int `json:"resources"`
+	Edges     int `json:"edges"`
+	// Deepest module nesting, 0 if there are only root module resources
+	MaxModuleDepth int `json:"max_module_depth"`
+	// Most dependencies of a single node
+	MaxFanOut        int    `json:"max_fan_out"`
+	MaxFanOutAddress string `json:"max_fan_out_address,omitempty"`
+	// Edges in the longest dependency chain, ignoring cycles
+	LongestChain     int      `json:"longest_chain"`
+	LongestChainPath []string `json:"longest_chain_path,omitempty"`
+}
+
+// Module calls in an address, like module.a.module.b
+var moduleSegment = regexp.MustCompile(`(^|\.)module\.`)
+
+// GenerateComplexity measures the full graph, before resource groups are
+// collapsed
+func (r *Rover) GenerateComplexity() *Complexity {
+	g := r.fullGraph
+	if len(g.Nodes) == 0 {
+		g = r.Graph
+	}
+
+	c := &Complexity{}
+	for id := range resourceNodes(g, r.RSO) {
+		c.Resources++
+		if d := len(moduleSegment.FindAllString(id, -1)); d > c.MaxModuleDepth {
+			c.MaxModuleDepth = d
+		}
+	}
+
+	edges := graphEdges(g)
+	c.Edges = len(edges)
+
+	// g.Cycles is stale once the graph is filtered, so find them again
+	cycles := findCycles(Graph{Nodes: g.Nodes, Edges: edges})
+	cyclic := cyclicEdges(Graph{Edges: edges, Cycles: cycles})
+	fanOut := make(map[string]int)
+	deps := make(map[string][]string)
+	for _, e := range edges {
+		fanOut[e.Data.Source]++
+		if !cyclic[e.Data.ID] {
+			deps[e.Data.Source] = append(deps[e.Data.Source], e.Data.Target)
+		}
+	}
+	for id, n := range fanOut {
+		if n > c.MaxFanOut || (n == c.MaxFanOut && id < c.MaxFanOutAddress) {
+			c.MaxFanOut = n
+			c.MaxFanOutAddress = id
+		}
+	}
+
+	// chains is the longest chain of dependencies from each node. Edges
+	// within cycles are skipped, so the rest of the graph is acyclic, and
+	// nodes being visited end a chain in case it isn't.
+	chains := make(map[string][]string)
+	visiting := make(map[string]bool)
+	var chain func(id string) []string
+	chain = func(id string) []string {
+		if ch, ok := chains[id]; ok {
+			return ch
+		}
+		if visiting[id] {
+			return nil
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+
+		var longest []string
+		for _, d := range deps[id] {
+			if d == id {
+				continue
+			}
+			dc := chain(d)
+			if len(dc) > len(longest) || (len(dc) == len(longest) && len(dc) > 0 && dc[0] < longest[0]) {
+				longest = dc
+			}
+		}
+
+		ch := append([]string{id}, longest...)
+		chains[id] = ch
+		return ch
+	}
+
+	for _, n := range g.Nodes {
+		ch := chain(n.Data.ID)
+		if len(ch)-1 > c.LongestChain || (len(ch)-1 == c.LongestChain && c.LongestChain > 0 && ch[0] < c.LongestChainPath[0]) {
+			c.LongestChain = len(ch) - 1
+			c.LongestChainPath = ch
+		}
+	}
+
+	return c
+}
+
+// resourceNodes returns the IDs of the graph's resource and data source
+// nodes. Resources with count or for_each are a single node, their instances'
+// nodes aren't included.
+func resourceNodes(g Graph, rso *ResourcesOverview) map[string]bool {
+	ids := make(map[string]bool)
+	if rso == nil {
+		return ids
+	}
+
+	for _, n := range g.Nodes {
+		so, ok := rso.States[n.Data.ID]
+		if !ok || (so.Type != ResourceTypeResource && so.Type != ResourceTypeData) {
+			continue
+		}
+		if p, ok := rso.States[n.Data.Parent]; ok && (p.Type == ResourceTypeResource || p.Type == ResourceTypeData) {
+			continue
+		}
+		ids[n.Data.ID] = true
+	}
+
+	return ids
+}
+
+// findCycles returns the strongly connected components of the graph with more
+// than one node, found with Tarjan's algorithm. Each cycle is sorted by address.
+func findCycles(g Graph) [][]string {
+	deps := make(map[string][]string)
+	for _, e := range graphEdges(g) {
+		deps[e.Data.Source] = append(deps[e.Data.Source], e.Data.Target)
+	}
+
+	index := 0
+	indices := make(map[string]int)
+	lowlinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var connect func(id string)
+	connect = func(id string) {
+		indices[id] = index
+		lowlinks[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, d := range deps[id] {
+			if _, ok := indices[d]; !ok {
+				connect(d)
+				if lowlinks[d] < lowlinks[id] {
+					lowlinks[id] = lowlinks[d]
+				}
+			} else if onStack[d] && indices[d] < lowlinks[id] {
+				lowlinks[id] = indices[d]
+			}
+		}
+
+		// id is the root of a strongly connected component
+		if lowlinks[id] == indices[id] {
+			var scc []string
+			for {
+				n := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[n] = false
+				scc = append(scc, n)
+				if n == id {
+					break
+				}
+			}
+
+			if len(scc) > 1 {
+				sort.Strings(scc)
+				cycles = append(cycles, scc)
+			}
+		}
+	}
+
+	for _, n := range g.Nodes {
+		if _, ok := indices[n.Data.ID]; !ok {
+			connect(n.Data.ID)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+
+	return cycles
+}
+
+// cyclicEdges returns the IDs of edges between nodes in the same cycle
+func cyclicEdges(g Graph) map[string]bool {
+	cycle := make(map[string]int)
+	for i, c := range g.Cycles {
+		for _, id := range c {
+			cycle[id] = i + 1
+		}
+	}
+
+	edges := make(map[string]bool)
+	for _, e := range g.Edges {
+		if c := cycle[e.Data.Source]; c != 0 && c == cycle[e.Data.Target] {
+			edges[e.Data.ID] = true
+		}
+	}
+	return edges
+}
+
+// markCycles finds the graph's dependency cycles and highlights the edges that
+// are part of one
+func markCycles(g *Graph) {
+	g.Cycles = findCycles(*g)
+	cyclic := cyclicEdges(*g)
+
+	for i, e := range g.Edges {
+		var classes []string
+		for _, c := range strings.Fields(e.Classes) {
+			if c != "cycle" {
+				classes = append(classes, c)
+			}
+		}
+		if cyclic[e.Data.ID] {
+			classes = append(classes, "cycle")
+		}
+		g.Edges[i].Classes = strings.Join(classes, " ")
+	}
+}
+
+// warnCycles logs the resources in each dependency cycle
+func warnCycles(cycles [][]string) {
+	for _, c := range cycles {
+		slog.Warn("Dependency cycle", "resources", strings.Join(c, ", "))
+	}
+}
@@ -0,0 +1,93 @@
+package rover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testGraph returns a graph with edges like "a->b", and a node for each
+// address in them and in nodes
+func testGraph(edges []string, nodes ...string) Graph {
+	g := Graph{}
+	seen := make(map[string]bool)
+	addNode := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			g.Nodes = append(g.Nodes, Node{Data: NodeData{ID: id, Label: id}})
+		}
+	}
+
+	for _, id := range nodes {
+		addNode(id)
+	}
+	for _, e := range edges {
+		source, target, _ := strings.Cut(e, "->")
+		addNode(source)
+		addNode(target)
+		g.Edges = append(g.Edges, Edge{Data: EdgeData{ID: e, Source: source, Target: target}})
+	}
+
+	return g
+}
+
+func TestGenerateComplexity(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges []string
+		// Found again if stale, like after filtering
+		cycles [][]string
+		chain  []string
+	}{
+		{
+			name:  "chain",
+			edges: []string{"a->b", "b->c", "a->c"},
+			chain: []string{"a", "b", "c"},
+		},
+		{
+			name:   "cycle",
+			edges:  []string{"a->b", "b->a", "b->c", "c->b", "d->a"},
+			cycles: [][]string{{"a", "b", "c"}},
+		},
+		{
+			name:  "stale cycles",
+			edges: []string{"a->b", "b->a", "b->c", "c->b", "d->a"},
+		},
+		{
+			name:  "self reference",
+			edges: []string{"a->a", "a->b"},
+			chain: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGraph(tt.edges)
+			g.Cycles = tt.cycles
+			r := New(Config{})
+			r.Graph = g
+
+			c := r.GenerateComplexity()
+			if c.Edges != len(tt.edges) {
+				t.Errorf("Edges = %d, want %d", c.Edges, len(tt.edges))
+			}
+
+			// Edges within cycles are skipped, leaving d->a
+			want := tt.chain
+			if want == nil {
+				want = []string{"d", "a"}
+			}
+			if c.LongestChain != len(want)-1 || !reflect.DeepEqual(c.LongestChainPath, want) {
+				t.Errorf("longest chain = %d %v, want %d %v", c.LongestChain, c.LongestChainPath, len(want)-1, want)
+			}
+		})
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	g := testGraph([]string{"a->b", "b->a", "c->d", "d->e", "e->c", "e->f", "f->f"}, "g")
+	want := [][]string{{"a", "b"}, {"c", "d", "e"}}
+	if got := findCycles(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("findCycles = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+package rover
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Resource (with an optional data prefix) and module addresses
+var anonymizeAddress = regexp.MustCompile(`(data\.)?([A-Za-z_][A-Za-z0-9_-]*)\.([A-Za-z_][A-Za-z0-9_-]*)`)
+
+// Names, optionally followed by an instance key or a collapsed count
+var anonymizeName = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)((\[.*\])?( \(\d+ resources\))?)$`)
+
+// anonymizer replaces resource and module names with generic names
+type anonymizer struct {
+	types      map[string]bool
+	resources  map[string]string
+	modules    map[string]string
+	workingDir string
+}
+
+// AnonymizeAssets replaces resource and module names in all assets with generic
+// names, like aws_instance.resource_1 and module.module_1, keeping resource
+// types and the structure. The working directory is replaced with ".". If
+// legend is set, Assets.Legend maps the generic names back to the originals.
+// Attribute values aren't changed.
+func (r *Rover) AnonymizeAssets(legend bool) error {
+	slog.Debug("Anonymizing resource names...")
+
+	a := &anonymizer{
+		types:     make(map[string]bool),
+		resources: make(map[string]string),
+		modules:   make(map[string]string),
+	}
+
+	var resources, modules []string
+	seen := make(map[string]bool)
+	for _, rc := range r.Plan.ResourceChanges {
+		a.types[rc.Type] = true
+		if !seen[rc.Name] {
+			seen[rc.Name] = true
+			resources = append(resources, rc.Name)
+		}
+		for _, m := range moduleNames(rc.ModuleAddress) {
+			if !seen["module."+m] {
+				seen["module."+m] = true
+				modules = append(modules, m)
+			}
+		}
+	}
+	sort.Strings(resources)
+	sort.Strings(modules)
+
+	for i, name := range resources {
+		a.resources[name] = fmt.Sprintf("resource_%d", i+1)
+	}
+	for i, name := range modules {
+		a.modules[name] = fmt.Sprintf("module_%d", i+1)
+	}
+
+	if dir, err := filepath.Abs(r.WorkingDir); err == nil && dir != "/" {
+		a.workingDir = dir
+	}
+
+	for _, asset := range []interface{}{&r.Plan, &r.RSO, &r.Map, &r.Graph, &r.fullGraph, &r.Meta, &r.Diff, &r.Providers} {
+		if err := a.anonymizeJSON(asset); err != nil {
+			return fmt.Errorf("Unable to anonymize assets: %s", err)
+		}
+	}
+
+	if r.Meta != nil && r.Meta.TfPath != "" {
+		r.Meta.TfPath = filepath.Base(r.Meta.TfPath)
+	}
+
+	if legend {
+		r.Legend = make(map[string]string, len(a.resources)+len(a.modules))
+		for name, anon := range a.resources {
+			r.Legend[anon] = name
+		}
+		for name, anon := range a.modules {
+			r.Legend["module."+anon] = "module." + name
+		}
+	}
+
+	return nil
+}
+
+// moduleNames returns the names of the modules in a module address
+func moduleNames(address string) []string {
+	var names []string
+	for _, m := range strings.Split(address, "module.")[1:] {
+		m = strings.TrimSuffix(m, ".")
+		if i := strings.Index(m, "["); i >= 0 {
+			m = m[:i]
+		}
+		names = append(names, m)
+	}
+	return names
+}
+
+// anonymizeJSON anonymizes the strings in v's JSON, then decodes it back into v
+func (a *anonymizer) anonymizeJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var j interface{}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	b, err = json.Marshal(a.anonymizeValue("", j))
+	if err != nil {
+		return err
+	}
+
+	// Unmarshaling into existing maps would keep their original keys
+	e := reflect.ValueOf(v).Elem()
+	e.Set(reflect.Zero(e.Type()))
+
+	return json.Unmarshal(b, v)
+}
+
+// anonymizeValue anonymizes the strings in v, the value of key
+func (a *anonymizer) anonymizeValue(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, c := range v {
+			ak := a.anonymizeString(k)
+			// Module calls are keyed by their name, provider configurations
+			// by their module and name
+			if key == "module_calls" {
+				if anon, ok := a.modules[k]; ok {
+					ak = anon
+				}
+			} else if key == "provider_config" {
+				ak = a.anonymizeProviderKey(k)
+			}
+			m[ak] = a.anonymizeValue(k, c)
+		}
+		return m
+	case []interface{}:
+		for i, c := range v {
+			v[i] = a.anonymizeValue(key, c)
+		}
+		return v
+	case string:
+		if key == "provider_config_key" {
+			return a.anonymizeProviderKey(v)
+		}
+		if key == "name" || key == "label" {
+			if s, ok := a.anonymizeBareName(v); ok {
+				return s
+			}
+		}
+		return a.anonymizeString(v)
+	default:
+		return v
+	}
+}
+
+// anonymizeString replaces the addresses and working directory in s
+func (a *anonymizer) anonymizeString(s string) string {
+	if a.workingDir != "" {
+		s = strings.ReplaceAll(s, a.workingDir, ".")
+	}
+
+	// Edge IDs join two addresses with an arrow, and names may contain dashes
+	if parts := strings.Split(s, "->"); len(parts) > 1 {
+		for i, p := range parts {
+			parts[i] = a.anonymizeString(p)
+		}
+		return strings.Join(parts, "->")
+	}
+
+	return anonymizeAddress.ReplaceAllStringFunc(s, func(m string) string {
+		parts := anonymizeAddress.FindStringSubmatch(m)
+		prefix, first, second := parts[1], parts[2], parts[3]
+
+		if prefix == "" && first == "module" {
+			if anon, ok := a.modules[second]; ok {
+				return "module." + anon
+			}
+		}
+		if a.types[first] {
+			if anon, ok := a.resources[second]; ok {
+				return prefix + first + "." + anon
+			}
+		}
+		return m
+	})
+}
+
+// anonymizeProviderKey replaces the module in a provider configuration key
+// like child:aws
+func (a *anonymizer) anonymizeProviderKey(s string) string {
+	if module, provider, ok := strings.Cut(s, ":"); ok {
+		if anon, ok := a.modules[module]; ok {
+			return anon + ":" + provider
+		}
+	}
+	return s
+}
+
+// anonymizeBareName replaces a resource or module name used on its own, like
+// graph labels and configuration names
+func (a *anonymizer) anonymizeBareName(s string) (string, bool) {
+	parts := anonymizeName.FindStringSubmatch(s)
+	if parts == nil {
+		return "", false
+	}
+
+	if anon, ok := a.resources[parts[1]]; ok {
+		return anon + parts[2], true
+	}
+	if anon, ok := a.modules[parts[1]]; ok {
+		return anon + parts[2], true
+	}
+	return "", false
+}
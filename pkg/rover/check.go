@@ -0,0 +1,169 @@
+package rover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// CheckResult is the resolved configuration Rover would generate assets with
+type CheckResult struct {
+	// Where the plan comes from: plan JSON, plan file, Terraform Cloud, plan or state
+	Source           string
+	PlanPath         string
+	WorkingDir       string
+	Product          string
+	TfPath           string
+	TerraformVersion string
+	Workspace        string
+	// Set if the workspace doesn't exist and would be created
+	CreateWorkspace bool
+	Resources       int
+	Modules         int
+	VarFiles        int
+	Vars            int
+	BackendConfigs  int
+	Targets         int
+}
+
+// Check validates the configuration without running init or plan. It resolves
+// the Terraform binary, loads the configuration and checks that the plan
+// files, var files and workspace exist.
+func (r *Rover) Check(ctx context.Context) (*CheckResult, error) {
+	res := &CheckResult{
+		WorkingDir:     r.WorkingDir,
+		Workspace:      r.WorkspaceName,
+		BackendConfigs: len(r.TfBackendConfigs),
+		Targets:        len(r.Targets),
+	}
+	for _, v := range r.TfVars {
+		if v.File {
+			res.VarFiles++
+		} else {
+			res.Vars++
+		}
+	}
+
+	if r.PlanJSONPath != "" {
+		res.Source = "plan JSON"
+		res.PlanPath = r.PlanJSONPath
+		return res, checkFile("Plan", r.PlanJSONPath)
+	}
+
+	if r.TFCRunID != "" {
+		res.Source = "Terraform Cloud run"
+		client, err := r.tfcClient()
+		if err == nil {
+			_, err = r.tfcRunPlan(ctx, client)
+		}
+		return res, err
+	}
+
+	if r.TFCWorkspaceName != "" {
+		res.Source = "Terraform Cloud"
+		res.Workspace = r.TFCWorkspaceName
+		return res, r.checkTFC(ctx)
+	}
+
+	err := checkWorkingDir(r.WorkingDir, r.PlanPath == "" && !r.FromState)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range r.TfVars {
+		if !v.File {
+			continue
+		}
+		if err := checkFile("tfvars file", v.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.PlanPath != "" {
+		res.Source = "plan file"
+		res.PlanPath = r.PlanPath
+		if err := checkFile("Plan", r.PlanPath); err != nil {
+			return nil, err
+		}
+	} else {
+		module, err := loadModule(r.WorkingDir)
+		if err != nil {
+			return nil, err
+		}
+		res.Resources = len(module.ManagedResources) + len(module.DataResources)
+		res.Modules = len(module.ModuleCalls)
+
+		res.Source = "plan"
+		if r.FromState {
+			res.Source = "state"
+		}
+	}
+
+	res.TfPath, err = findTerraform(r.TfPath)
+	if err != nil {
+		return nil, err
+	}
+	res.Product = tfProduct(res.TfPath)
+
+	tf, err := r.newTerraform(res.TfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tfVersion, _, err := tf.Version(ctx, false)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to get %s version: %s", res.Product, err))
+	}
+	res.TerraformVersion = tfVersion.String()
+
+	if r.WorkspaceName != "" && r.PlanPath == "" {
+		workspaces, _, err := tf.WorkspaceList(ctx)
+		if err != nil {
+			// Remote backends list workspaces only once initialized
+			slog.Warn("Unable to list workspaces, is the working directory initialized?", "error", err)
+			return res, nil
+		}
+
+		res.CreateWorkspace = true
+		for _, ws := range workspaces {
+			if ws == r.WorkspaceName {
+				res.CreateWorkspace = false
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// checkTFC checks that the Terraform Cloud workspace exists
+func (r *Rover) checkTFC(ctx context.Context) error {
+	if r.TFCOrgName == "" {
+		return errors.New("Must specify Terraform Cloud organization to retrieve plan from Terraform Cloud")
+	}
+
+	client, err := r.tfcClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Workspaces.Read(ctx, r.TFCOrgName, r.TFCWorkspaceName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to list workspace %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+	}
+
+	return nil
+}
+
+// checkFile returns an error if path doesn't exist or is a directory
+func checkFile(kind string, path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to find %s (%s): %s", kind, path, err))
+	}
+	if fi.IsDir() {
+		return errors.New(fmt.Sprintf("Unable to read %s (%s): path is a directory", kind, path))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package rover
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// collapseGroups replaces the resources of resource type groups with at least
+// threshold resources by the group node, labeled with the count. Edges to the
+// resources are moved to the group. Groups in expand, or all groups if expand
+// contains "*", aren't collapsed.
+func collapseGroups(g Graph, threshold int, expand map[string]bool) Graph {
+	if threshold <= 0 || expand["*"] {
+		return g
+	}
+
+	children, _ := graphTree(g)
+
+	// Resources and instances of collapsed groups, mapped to their group
+	group := make(map[string]string)
+	collapsed := make(map[string]Node)
+
+	for _, n := range g.Nodes {
+		if (n.Data.Type != ResourceTypeResource && n.Data.Type != ResourceTypeData) || n.Data.Parent == "" || expand[n.Data.ID] {
+			continue
+		}
+		// Resource type nodes group the resources of a type in a file. Resources
+		// with instances are typed the same, they're collapsed with their group
+		// if it is.
+		if _, ok := group[n.Data.ID]; ok || n.Classes != fmt.Sprintf("%s-type", n.Data.Type) {
+			continue
+		}
+
+		var descendants, leaves []Node
+		var walk func(id string)
+		walk = func(id string) {
+			for _, c := range children[id] {
+				descendants = append(descendants, c)
+				if len(children[c.Data.ID]) == 0 {
+					leaves = append(leaves, c)
+				}
+				walk(c.Data.ID)
+			}
+		}
+		walk(n.Data.ID)
+
+		if len(leaves) == 0 || len(leaves) < threshold {
+			continue
+		}
+
+		for _, d := range descendants {
+			group[d.Data.ID] = n.Data.ID
+		}
+
+		c := n
+		c.Data.Label = fmt.Sprintf("%s (%d resources)", n.Data.Label, len(leaves))
+		c.Data.Collapsed = true
+		c.Data.ChildCount = len(leaves)
+		for _, l := range leaves {
+			c.Data.Change = string(mergeActions(Action(c.Data.Change), Action(l.Data.Change)))
+			c.Data.Drift = c.Data.Drift || l.Data.Drift
+		}
+		c.Classes = fmt.Sprintf("%s collapsed %s", n.Classes, c.Data.Change)
+		collapsed[n.Data.ID] = c
+	}
+
+	if len(collapsed) == 0 {
+		return g
+	}
+
+	slog.Debug("Collapsing resource groups...", "groups", len(collapsed), "threshold", threshold)
+
+	nodes := make([]Node, 0, len(g.Nodes)-len(group))
+	for _, n := range g.Nodes {
+		if c, ok := collapsed[n.Data.ID]; ok {
+			nodes = append(nodes, c)
+		} else if _, ok := group[n.Data.ID]; !ok {
+			nodes = append(nodes, n)
+		}
+	}
+
+	resolve := func(id string) string {
+		if gid, ok := group[id]; ok {
+			return gid
+		}
+		return id
+	}
+
+	seen := make(map[string]bool)
+	edges := make([]Edge, 0, len(g.Edges))
+	for _, e := range g.Edges {
+		e.Data.Source = resolve(e.Data.Source)
+		e.Data.Target = resolve(e.Data.Target)
+		e.Data.ID = fmt.Sprintf("%s->%s", e.Data.Source, e.Data.Target)
+		if e.Data.Source == e.Data.Target || seen[e.Data.ID] {
+			continue
+		}
+		seen[e.Data.ID] = true
+		edges = append(edges, e)
+	}
+
+	// Groups are in the cycles of their resources
+	collapsedGraph := Graph{
+		Nodes: nodes,
+		Edges: edges,
+	}
+	markCycles(&collapsedGraph)
+
+	return collapsedGraph
+}
+
+// mergeActions returns the action shared by a and b, or update if they
+// differ. No-op and unset actions take the other action.
+func mergeActions(a Action, b Action) Action {
+	switch {
+	case a == "" || a == ActionNoop:
+		if b == "" {
+			return a
+		}
+		return b
+	case b == "" || b == ActionNoop || a == b:
+		return a
+	default:
+		return ActionUpdate
+	}
+}
+
+// ExpandGroups returns a copy of r whose graph only collapses the resource
+// type groups that aren't in expand. "*" expands all groups.
+func (r *Rover) ExpandGroups(expand []string) *Rover {
+	if r.CollapseThreshold <= 0 {
+		return r
+	}
+
+	e := make(map[string]bool, len(expand))
+	for _, id := range expand {
+		e[id] = true
+	}
+
+	c := *r
+	c.Graph = collapseGroups(r.fullGraph, r.CollapseThreshold, e)
+	return &c
+}
@@ -0,0 +1,39 @@
+package rover
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCollapseGroupsCycles checks a collapsed group is in the cycles of its
+// resources
+func TestCollapseGroupsCycles(t *testing.T) {
+	g := testGraph([]string{
+		"aws_instance.a->aws_s3_bucket.c",
+		"aws_s3_bucket.c->aws_instance.a",
+		"aws_instance.b->aws_s3_bucket.c",
+	}, "main.tf", "aws_instance", "aws_s3_bucket")
+	for i, n := range g.Nodes {
+		d := &g.Nodes[i].Data
+		switch n.Data.ID {
+		case "main.tf":
+			d.Type = ResourceTypeFile
+		case "aws_instance", "aws_s3_bucket":
+			d.Type = ResourceTypeResource
+			d.Parent = "main.tf"
+			g.Nodes[i].Classes = "resource-type"
+		default:
+			d.Type = ResourceTypeResource
+			d.Parent, _, _ = strings.Cut(n.Data.ID, ".")
+		}
+	}
+	markCycles(&g)
+	checkCycles(t, g, [][]string{{"aws_instance.a", "aws_s3_bucket.c"}})
+
+	c := collapseGroups(g, 2, nil)
+
+	if len(c.Nodes) != len(g.Nodes)-2 {
+		t.Fatalf("collapsed graph has %d nodes, want aws_instance's 2 resources collapsed", len(c.Nodes))
+	}
+	checkCycles(t, c, [][]string{{"aws_instance", "aws_s3_bucket.c"}})
+}
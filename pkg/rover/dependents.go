@@ -0,0 +1,107 @@
+package rover
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DependencyResult is a node reachable from an address in the graph
+type DependencyResult struct {
+	Address string       `json:"address"`
+	Type    ResourceType `json:"type,omitempty"`
+	// Number of edges between the address and the node
+	Depth int `json:"depth"`
+	// Shortest path from the address to the node, both included
+	Path []string `json:"path"`
+}
+
+// Dependents returns the nodes that depend on addr, directly or transitively,
+// up to depth edges away. A depth of 0 doesn't limit the traversal.
+func (r *Rover) Dependents(addr string, depth int) ([]DependencyResult, error) {
+	return r.traverse(addr, depth, true)
+}
+
+// Dependencies returns the nodes addr depends on, directly or transitively,
+// up to depth edges away. A depth of 0 doesn't limit the traversal.
+func (r *Rover) Dependencies(addr string, depth int) ([]DependencyResult, error) {
+	return r.traverse(addr, depth, false)
+}
+
+// traverse walks the graph breadth-first from addr, following edges from
+// dependencies to dependents if reverse is set, so each node is reached by
+// its shortest path
+func (r *Rover) traverse(addr string, depth int, reverse bool) ([]DependencyResult, error) {
+	// Collapsed groups hide resources, so walk the full graph
+	g := r.fullGraph
+	if len(g.Nodes) == 0 {
+		g = r.Graph
+	}
+
+	types := make(map[string]ResourceType, len(g.Nodes))
+	for _, n := range g.Nodes {
+		types[n.Data.ID] = n.Data.Type
+	}
+
+	// Instances are part of their resource's node
+	start := addr
+	if _, ok := types[start]; !ok {
+		if i := strings.LastIndex(addr, "["); i > 0 {
+			start = addr[:i]
+		}
+	}
+	if _, ok := types[start]; !ok {
+		return nil, errors.New(fmt.Sprintf("Unable to find %s in the graph", addr))
+	}
+
+	next := make(map[string][]string)
+	for _, e := range graphEdges(g) {
+		from, to := e.Data.Source, e.Data.Target
+		if reverse {
+			from, to = to, from
+		}
+		next[from] = append(next[from], to)
+	}
+
+	paths := map[string][]string{start: {start}}
+	results := []DependencyResult{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		p := paths[id]
+		if depth > 0 && len(p)-1 >= depth {
+			continue
+		}
+
+		// Visit neighbors in a stable order so paths don't change between runs
+		neighbors := next[id]
+		sort.Strings(neighbors)
+		for _, n := range neighbors {
+			if _, ok := paths[n]; ok {
+				continue
+			}
+
+			np := append(append([]string{}, p...), n)
+			paths[n] = np
+			queue = append(queue, n)
+			results = append(results, DependencyResult{
+				Address: n,
+				Type:    types[n],
+				Depth:   len(np) - 1,
+				Path:    np,
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Depth != results[j].Depth {
+			return results[i].Depth < results[j].Depth
+		}
+		return results[i].Address < results[j].Address
+	})
+
+	return results, nil
+}
@@ -0,0 +1,149 @@
+package rover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTraverse(t *testing.T) {
+	// aws_instance.web depends on the security group and subnet, which both
+	// depend on the VPC, and the load balancer depends on the instance
+	g := testGraph([]string{
+		"aws_instance.web->aws_security_group.web",
+		"aws_instance.web->aws_subnet.web",
+		"aws_subnet.web->aws_vpc.main",
+		"aws_security_group.web->aws_vpc.main",
+		"aws_lb.web->aws_instance.web",
+		"null_resource.a->null_resource.b",
+		"null_resource.b->null_resource.c",
+		"null_resource.c->null_resource.a",
+	}, "aws_s3_bucket.logs")
+
+	type result struct {
+		address string
+		path    string
+	}
+	tests := []struct {
+		name    string
+		addr    string
+		depth   int
+		reverse bool
+		want    []result
+		err     string
+	}{
+		{
+			name: "dependencies",
+			addr: "aws_instance.web",
+			want: []result{
+				{"aws_security_group.web", "aws_instance.web aws_security_group.web"},
+				{"aws_subnet.web", "aws_instance.web aws_subnet.web"},
+				// Both paths are as short, the first neighbor's is used
+				{"aws_vpc.main", "aws_instance.web aws_security_group.web aws_vpc.main"},
+			},
+		},
+		{
+			name:    "dependents",
+			addr:    "aws_vpc.main",
+			reverse: true,
+			want: []result{
+				{"aws_security_group.web", "aws_vpc.main aws_security_group.web"},
+				{"aws_subnet.web", "aws_vpc.main aws_subnet.web"},
+				{"aws_instance.web", "aws_vpc.main aws_security_group.web aws_instance.web"},
+				{"aws_lb.web", "aws_vpc.main aws_security_group.web aws_instance.web aws_lb.web"},
+			},
+		},
+		{
+			name:    "depth",
+			addr:    "aws_vpc.main",
+			depth:   2,
+			reverse: true,
+			want: []result{
+				{"aws_security_group.web", "aws_vpc.main aws_security_group.web"},
+				{"aws_subnet.web", "aws_vpc.main aws_subnet.web"},
+				{"aws_instance.web", "aws_vpc.main aws_security_group.web aws_instance.web"},
+			},
+		},
+		{
+			name:  "depth 1",
+			addr:  "aws_lb.web",
+			depth: 1,
+			want: []result{
+				{"aws_instance.web", "aws_lb.web aws_instance.web"},
+			},
+		},
+		{
+			name:    "instance",
+			addr:    `aws_instance.web["a"]`,
+			reverse: true,
+			want: []result{
+				{"aws_lb.web", "aws_instance.web aws_lb.web"},
+			},
+		},
+		{
+			name: "cycle",
+			addr: "null_resource.a",
+			want: []result{
+				{"null_resource.b", "null_resource.a null_resource.b"},
+				{"null_resource.c", "null_resource.a null_resource.b null_resource.c"},
+			},
+		},
+		{
+			name:    "cycle dependents",
+			addr:    "null_resource.a",
+			reverse: true,
+			want: []result{
+				{"null_resource.c", "null_resource.a null_resource.c"},
+				{"null_resource.b", "null_resource.a null_resource.c null_resource.b"},
+			},
+		},
+		{
+			name: "no dependencies",
+			addr: "aws_s3_bucket.logs",
+			want: []result{},
+		},
+		{
+			name: "unknown",
+			addr: "aws_instance.missing",
+			err:  "Unable to find aws_instance.missing",
+		},
+		{
+			name: "unknown instance",
+			addr: "aws_instance.missing[0]",
+			err:  "Unable to find aws_instance.missing[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Rover{}
+			r.Graph = g
+			traverse := r.Dependencies
+			if tt.reverse {
+				traverse = r.Dependents
+			}
+
+			results, err := traverse(tt.addr, tt.depth)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []result{}
+			for _, res := range results {
+				if res.Depth != len(res.Path)-1 {
+					t.Errorf("%s: depth %d for path %v", res.Address, res.Depth, res.Path)
+				}
+				got = append(got, result{res.Address, strings.Join(res.Path, " ")})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package rover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Status of a resource relative to the -comparePlan baseline
+const (
+	DiffAdded    string = "added"
+	DiffRemoved  string = "removed"
+	DiffModified string = "modified"
+)
+
+// Diff is the difference between the plan and the -comparePlan baseline
+type Diff struct {
+	Baseline string   `json:"baseline"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// GenerateDiff compares the plan's resource changes to the baseline plan and
+// annotates the resource overview and graph with each resource's status
+func (r *Rover) GenerateDiff(ctx context.Context) error {
+	slog.Debug("Comparing plans...", "baseline", r.ComparePlanPath)
+
+	baseline, err := r.loadComparePlan(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.checkPlanFormatVersion("Comparison plan", baseline)
+	if err != nil {
+		return err
+	}
+
+	if !r.ShowSensitive {
+		redactPlan(baseline)
+	}
+
+	before := make(map[string]*tfjson.ResourceChange, len(baseline.ResourceChanges))
+	for _, rc := range baseline.ResourceChanges {
+		before[rc.Address] = rc
+	}
+
+	diff := &Diff{
+		Baseline: r.ComparePlanPath,
+		Added:    []string{},
+		Removed:  []string{},
+		Modified: []string{},
+	}
+	status := make(map[string]string)
+
+	for _, rc := range r.Plan.ResourceChanges {
+		b, ok := before[rc.Address]
+		delete(before, rc.Address)
+
+		if !ok {
+			diff.Added = append(diff.Added, rc.Address)
+			status[rc.Address] = DiffAdded
+		} else if !reflect.DeepEqual(rc.Change, b.Change) {
+			diff.Modified = append(diff.Modified, rc.Address)
+			status[rc.Address] = DiffModified
+		}
+	}
+
+	for address := range before {
+		diff.Removed = append(diff.Removed, address)
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+
+	for address, s := range status {
+		if so, ok := r.RSO.States[address]; ok {
+			so.Diff = s
+		}
+	}
+
+	for i, n := range r.Graph.Nodes {
+		if s, ok := status[n.Data.ID]; ok {
+			r.Graph.Nodes[i].Data.Diff = s
+			r.Graph.Nodes[i].Classes = fmt.Sprintf("%s diff-%s", n.Classes, s)
+		}
+	}
+
+	r.Diff = diff
+
+	return nil
+}
+
+// loadComparePlan reads the baseline plan, either a JSON plan or a plan file
+func (r *Rover) loadComparePlan(ctx context.Context) (*tfjson.Plan, error) {
+	planBytes, err := os.ReadFile(r.ComparePlanPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read comparison Plan (%s): %s", r.ComparePlanPath, err))
+	}
+
+	planBytes, err = gunzip(planBytes)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read comparison Plan (%s): %s", r.ComparePlanPath, err))
+	}
+
+	plan := &tfjson.Plan{}
+	if json.Valid(planBytes) {
+		if err := json.Unmarshal(planBytes, plan); err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to read comparison Plan (%s): %s", r.ComparePlanPath, err))
+		}
+		return plan, nil
+	}
+
+	// Plan files are read with terraform show
+	tfPath := r.TfPath
+	if tfPath == "" {
+		tfPath, err = findTerraform("")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tf, err := r.newTerraform(tfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err = tf.ShowPlanFile(ctx, r.ComparePlanPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read comparison Plan (%s), is it a Terraform plan file? %s", r.ComparePlanPath, err))
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,152 @@
+package rover
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Fill colors for resources that change
+var changeColors = map[string]string{
+	string(ActionCreate):  "#d4edda",
+	string(ActionUpdate):  "#fff3cd",
+	string(ActionDelete):  "#f8d7da",
+	string(ActionReplace): "#e2d4f0",
+	string(ActionRead):    "#d1ecf1",
+}
+
+// Line styles for edges that aren't plain references
+var edgeStyles = map[EdgeKind]string{
+	EdgeKindDependsOn:  "dashed",
+	EdgeKindModuleCall: "dotted",
+}
+
+// GenerateDOT renders the graph in Graphviz DOT format. Nodes with children
+// (modules, files and resource types) are rendered as clusters.
+func (r *Rover) GenerateDOT() []byte {
+	var b bytes.Buffer
+
+	children, roots := graphTree(r.Graph)
+
+	// Edges can't point to clusters, so clusters referenced by an edge get a node too
+	endpoints := make(map[string]bool)
+	for _, e := range graphEdges(r.Graph) {
+		endpoints[e.Data.Source] = true
+		endpoints[e.Data.Target] = true
+	}
+
+	b.WriteString("digraph rover {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=white, fontname=Helvetica];\n")
+	b.WriteString("  edge [color=gray40];\n")
+
+	cluster := 0
+	var writeNode func(n Node, indent string)
+	writeNode = func(n Node, indent string) {
+		if len(children[n.Data.ID]) == 0 {
+			b.WriteString(indent + dotNode(n) + "\n")
+			return
+		}
+
+		fmt.Fprintf(&b, "%ssubgraph cluster_%d {\n", indent, cluster)
+		cluster++
+		fmt.Fprintf(&b, "%s  label=%s;\n", indent, dotQuote(n.Data.Label))
+		fmt.Fprintf(&b, "%s  color=%s;\n", indent, dotQuote(getResourceColor(n.Data.Type)))
+		if endpoints[n.Data.ID] {
+			b.WriteString(indent + "  " + dotNode(n) + "\n")
+		}
+		for _, c := range children[n.Data.ID] {
+			writeNode(c, indent+"  ")
+		}
+		b.WriteString(indent + "}\n")
+	}
+
+	for _, n := range roots {
+		writeNode(n, "  ")
+	}
+
+	cyclic := cyclicEdges(r.Graph)
+	for _, e := range graphEdges(r.Graph) {
+		var attrs []string
+		if cyclic[e.Data.ID] {
+			attrs = append(attrs, "color=red", "penwidth=2")
+		}
+		if style, ok := edgeStyles[e.Data.Kind]; ok {
+			attrs = append(attrs, fmt.Sprintf("style=%s", style))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.Data.Source), dotQuote(e.Data.Target), strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(e.Data.Source), dotQuote(e.Data.Target))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+// graphTree returns the children of each node and the nodes without a parent,
+// in the order they appear in the graph
+func graphTree(g Graph) (map[string][]Node, []Node) {
+	ids := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		ids[n.Data.ID] = true
+	}
+
+	children := make(map[string][]Node)
+	var roots []Node
+	for _, n := range g.Nodes {
+		if n.Data.Parent == "" || !ids[n.Data.Parent] || n.Data.Parent == n.Data.ID {
+			roots = append(roots, n)
+			continue
+		}
+		children[n.Data.Parent] = append(children[n.Data.Parent], n)
+	}
+
+	return children, roots
+}
+
+// graphEdges returns the edges between nodes in the graph, skipping
+// references to nodes that weren't generated
+func graphEdges(g Graph) []Edge {
+	ids := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		ids[n.Data.ID] = true
+	}
+
+	var edges []Edge
+	for _, e := range g.Edges {
+		if ids[e.Data.Source] && ids[e.Data.Target] {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+func dotNode(n Node) string {
+	attrs := []string{
+		fmt.Sprintf("label=%s", dotQuote(n.Data.ID)),
+		fmt.Sprintf("color=%s", dotQuote(getResourceColor(n.Data.Type))),
+	}
+	if fill, ok := changeColors[n.Data.Change]; ok {
+		attrs = append(attrs, fmt.Sprintf("fillcolor=%s", dotQuote(fill)))
+	} else if n.Data.Drift {
+		attrs = append(attrs, fmt.Sprintf("fillcolor=%s", dotQuote(driftColor)))
+	}
+	if n.Data.Drift {
+		attrs = append(attrs, "penwidth=2", `style="rounded,filled,dashed"`)
+	}
+	// Data sources are read, not changed
+	if n.Data.Type == ResourceTypeData {
+		attrs = append(attrs, "shape=ellipse")
+	}
+	return fmt.Sprintf("%s [%s];", dotQuote(n.Data.ID), strings.Join(attrs, ", "))
+}
+
+// dotQuote returns s as a quoted DOT ID
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,46 @@
+package rover
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Colors for resources that changed outside of Terraform
+const (
+	driftColor  = "#ffd8b1"
+	driftStroke = "#fd7e14"
+)
+
+// GenerateDrift marks the resources that refresh found changed outside of
+// Terraform in the resource overview and graph, and counts them in the summary
+func (r *Rover) GenerateDrift() {
+	if len(r.Plan.ResourceDrift) == 0 {
+		return
+	}
+
+	slog.Debug("Marking drifted resources...", "count", len(r.Plan.ResourceDrift))
+
+	drifted := make(map[string]bool, len(r.Plan.ResourceDrift))
+	for _, rc := range r.Plan.ResourceDrift {
+		if rc.Change == nil || rc.Change.Actions.NoOp() {
+			continue
+		}
+		drifted[rc.Address] = true
+	}
+
+	for address := range drifted {
+		if so, ok := r.RSO.States[address]; ok {
+			so.Drift = true
+		}
+	}
+	if r.RSO.Summary != nil {
+		r.RSO.Summary.Drift = len(drifted)
+	}
+
+	for i, n := range r.Graph.Nodes {
+		if drifted[n.Data.ID] {
+			r.Graph.Nodes[i].Data.Drift = true
+			r.Graph.Nodes[i].Classes = fmt.Sprintf("%s drift", n.Classes)
+		}
+	}
+}
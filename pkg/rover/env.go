@@ -0,0 +1,139 @@
+package rover
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+)
+
+// Prefix of environment variables that set Terraform variables
+const envVarPrefix = "TF_VAR_"
+
+// newTerraform returns tfexec for the working directory, running Terraform
+// with Rover's environment overridden by Config.Env. Rover's own environment
+// is left unchanged.
+func (r *Rover) newTerraform(tfPath string) (*tfexec.Terraform, error) {
+	tf, err := tfexec.NewTerraform(r.WorkingDir, tfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	env, managed := r.terraformEnv()
+	err = tf.SetEnv(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// tfexec only logs at TRACE, and only to a file so the log doesn't mix
+	// with Terraform's output
+	logPath := r.TfLogPath
+	if logPath == "" && managed["TF_LOG"] != "" && !strings.EqualFold(managed["TF_LOG"], "off") {
+		logPath = managed["TF_LOG_PATH"]
+	}
+	if logPath != "" {
+		err = tf.SetLogPath(logPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for k := range managed {
+		if k != "TF_LOG" && k != "TF_LOG_PATH" && !strings.HasPrefix(k, envVarPrefix) {
+			slog.Debug("Not passing environment variable to Terraform, tfexec manages it", "name", k)
+		}
+	}
+
+	return tf, nil
+}
+
+// terraformEnv returns Rover's environment overridden by Config.Env, split
+// into what tfexec.SetEnv accepts and the variables tfexec manages itself,
+// like TF_LOG and TF_VAR_name
+func (r *Rover) terraformEnv() (map[string]string, map[string]string) {
+	env := make(map[string]string)
+	for _, e := range os.Environ() {
+		// Windows has variables like =C: for the drives' working directories
+		k, v, ok := strings.Cut(e, "=")
+		if ok && k != "" {
+			env[k] = v
+		}
+	}
+	for k, v := range r.Env {
+		env[k] = v
+	}
+
+	managed := make(map[string]string)
+	for _, k := range tfexec.ProhibitedEnv(env) {
+		managed[k] = env[k]
+		delete(env, k)
+	}
+
+	return env, managed
+}
+
+// envVars returns the TF_VAR_name variables in Terraform's environment as
+// name=value for -var, as tfexec doesn't set them in the environment.
+// Variables from the environment have the lowest precedence, so those a
+// *.tfvars file or -var assigns are left out. Like Terraform, undeclared
+// variables are ignored, -var would reject them.
+func (r *Rover) envVars() []string {
+	_, managed := r.terraformEnv()
+
+	values := make(map[string]string)
+	for k, v := range managed {
+		if name := strings.TrimPrefix(k, envVarPrefix); name != k && name != "" {
+			values[name] = v
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	module, err := loadModule(r.WorkingDir)
+	if err != nil {
+		slog.Warn("Unable to load configuration, ignoring TF_VAR_ environment variables", "error", err)
+		return nil
+	}
+	assigned := r.assignedVariables()
+
+	var vars []string
+	for name, v := range values {
+		if _, ok := module.Variables[name]; ok && !assigned[name] {
+			vars = append(vars, name+"="+v)
+		}
+	}
+	sort.Strings(vars)
+
+	return vars
+}
+
+// assignedVariables returns the variables that terraform.tfvars,
+// *.auto.tfvars, -var-file and -var assign
+func (r *Rover) assignedVariables() map[string]bool {
+	assigned := make(map[string]bool)
+
+	files := []string{"terraform.tfvars", "terraform.tfvars.json"}
+	for _, pattern := range []string{"*.auto.tfvars", "*.auto.tfvars.json"} {
+		matches, _ := filepath.Glob(filepath.Join(r.WorkingDir, pattern))
+		files = append(files, matches...)
+	}
+	for _, v := range r.TfVars {
+		if v.File {
+			files = append(files, v.Value)
+		} else if name, _, ok := strings.Cut(v.Value, "="); ok {
+			assigned[strings.TrimSpace(name)] = true
+		}
+	}
+
+	for _, f := range files {
+		for name := range r.fileVariables(f) {
+			assigned[name] = true
+		}
+	}
+
+	return assigned
+}
@@ -0,0 +1,93 @@
+package rover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTerraformEnv(t *testing.T) {
+	t.Setenv("ROVER_TEST_INHERITED", "inherited")
+	t.Setenv("ROVER_TEST_OVERRIDDEN", "inherited")
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf": `variable "region" {}
+variable "tfvars" {}
+variable "var_file" {}
+variable "var" {}
+`,
+		"terraform.tfvars": "tfvars = \"file\"\n",
+		"prod.tfvars":      "var_file = \"file\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Record the environment and arguments plan runs with
+	out := t.TempDir()
+	logPath := filepath.Join(out, "terraform.log")
+	r := New(Config{
+		Name:       "rover",
+		WorkingDir: dir,
+		TfPath:     fakeTerraform(t, map[string]string{"plan": `env > "` + out + `/env"; echo "$@" > "` + out + `/args"; exit 1`}),
+		TfVars:     []TfVar{{Value: "prod.tfvars", File: true}, {Value: "var=cli"}},
+		Env: map[string]string{
+			"ROVER_TEST_OVERRIDDEN": "explicit",
+			"ROVER_TEST_EXPLICIT":   "explicit",
+			"TF_LOG":                "DEBUG",
+			"TF_LOG_PATH":           logPath,
+			"TF_VAR_region":         "us-east-1",
+			"TF_VAR_tfvars":         "env",
+			"TF_VAR_var_file":       "env",
+			"TF_VAR_var":            "env",
+			"TF_VAR_undeclared":     "env",
+		},
+	})
+	if err := r.Generate(context.Background()); err == nil {
+		t.Fatal("Generate succeeded, want plan error")
+	}
+
+	if v := os.Getenv("ROVER_TEST_EXPLICIT"); v != "" {
+		t.Errorf("-env set ROVER_TEST_EXPLICIT=%s in Rover's environment", v)
+	}
+
+	env, err := os.ReadFile(filepath.Join(out, "env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ROVER_TEST_INHERITED=inherited",
+		"ROVER_TEST_OVERRIDDEN=explicit",
+		"ROVER_TEST_EXPLICIT=explicit",
+		// tfexec only logs at TRACE
+		"TF_LOG=TRACE",
+		"TF_LOG_PATH=" + logPath,
+	} {
+		if !strings.Contains("\n"+string(env), "\n"+want+"\n") {
+			t.Errorf("Terraform's environment is missing %s", want)
+		}
+	}
+	if strings.Contains(string(env), "TF_VAR_") {
+		t.Errorf("Terraform's environment has TF_VAR_ variables, tfexec rejects them:\n%s", env)
+	}
+
+	args, err := os.ReadFile(filepath.Join(out, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "-var region=us-east-1") {
+		t.Errorf("plan arguments are missing TF_VAR_region: %s", args)
+	}
+	// Files and -var override the environment, and undeclared variables
+	// would be rejected
+	for _, v := range []string{"tfvars=env", "var_file=env", "var=env", "undeclared=env"} {
+		if strings.Contains(string(args), v) {
+			t.Errorf("plan arguments have %s: %s", v, args)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package rover
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// filterAssets prunes the graph and map to resources matching r.Filters and
+// their immediate neighbors. The plan and resource overview are left complete.
+func (r *Rover) filterAssets() {
+	slog.Debug("Filtering graph and map...", "filters", strings.Join(r.Filters, ", "))
+
+	keep := make(map[string]bool)
+	for _, n := range r.Graph.Nodes {
+		if matchesFilters(n.Data.ID, r.Filters) {
+			keep[n.Data.ID] = true
+		}
+	}
+
+	// Immediate neighbors
+	for _, e := range r.Graph.Edges {
+		if matchesFilters(e.Data.Source, r.Filters) || matchesFilters(e.Data.Target, r.Filters) {
+			keep[e.Data.Source] = true
+			keep[e.Data.Target] = true
+		}
+	}
+
+	r.pruneAssets(keep)
+}
+
+// filterTypes removes the resources whose type doesn't match r.IncludeTypes,
+// if set, or matches r.ExcludeTypes from the resource overview, map and
+// graph. Edges through removed resources are replaced by edges between the
+// resources they connected. The plan is left complete.
+func (r *Rover) filterTypes() {
+	slog.Debug("Filtering resource types...", "include", strings.Join(r.IncludeTypes, ", "), "exclude", strings.Join(r.ExcludeTypes, ", "))
+
+	removed := make(map[string]bool)
+	for _, n := range r.Graph.Nodes {
+		if (n.Data.Type == ResourceTypeResource || n.Data.Type == ResourceTypeData) && !r.typeIncluded(n.Data.ID) {
+			removed[n.Data.ID] = true
+		}
+	}
+
+	children, _ := graphTree(r.Graph)
+	keep := make(map[string]bool)
+	for _, n := range r.Graph.Nodes {
+		if len(children[n.Data.ID]) == 0 && !removed[n.Data.ID] {
+			keep[n.Data.ID] = true
+		}
+	}
+
+	cycles := make(map[string]bool, len(r.Graph.Cycles))
+	for _, c := range r.Graph.Cycles {
+		cycles[strings.Join(c, ", ")] = true
+	}
+
+	r.Graph.Edges = append(r.Graph.Edges, bridgeEdges(r.Graph, removed)...)
+	r.pruneAssets(keep)
+
+	// Cycles through removed resources are now between the resources bridged
+	// around them
+	for _, c := range r.Graph.Cycles {
+		if resources := strings.Join(c, ", "); !cycles[resources] {
+			slog.Warn("Dependency cycle through filtered resources", "resources", resources)
+		}
+	}
+
+	if r.RSO != nil {
+		for address, so := range r.RSO.States {
+			if (so.Type == ResourceTypeResource || so.Type == ResourceTypeData) && !r.typeIncluded(address) {
+				delete(r.RSO.States, address)
+			}
+		}
+		for _, so := range r.RSO.States {
+			for id := range so.Children {
+				if _, ok := r.RSO.States[id]; !ok {
+					delete(so.Children, id)
+				}
+			}
+		}
+	}
+}
+
+// typeIncluded reports whether the type of the resource at address matches
+// the -includeType and -excludeType glob patterns
+func (r *Rover) typeIncluded(address string) bool {
+	resourceType, _, _ := strings.Cut(addressPrefix.ReplaceAllString(address, ""), ".")
+
+	matches := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, resourceType); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(r.IncludeTypes) > 0 && !matches(r.IncludeTypes) {
+		return false
+	}
+	return !matches(r.ExcludeTypes)
+}
+
+// matchesFilters reports whether the address matches one of the glob patterns,
+// or is within a module or resource matching one of them
+func matchesFilters(address string, filters []string) bool {
+	for _, f := range filters {
+		if address == f || strings.HasPrefix(address, f+".") || strings.HasPrefix(address, f+"[") {
+			return true
+		}
+		if ok, _ := path.Match(f, address); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneAssets removes the graph nodes and map resources that aren't kept.
+// Containers (modules, files and resource types) are kept if any of their
+// children are.
+func (r *Rover) pruneAssets(keep map[string]bool) {
+	parents := make(map[string]string, len(r.Graph.Nodes))
+	for _, n := range r.Graph.Nodes {
+		parents[n.Data.ID] = n.Data.Parent
+	}
+
+	// Keep the containers of kept nodes
+	for id := range keep {
+		for p := parents[id]; p != "" && !keep[p]; p = parents[p] {
+			keep[p] = true
+		}
+	}
+
+	nodes := make([]Node, 0, len(keep))
+	for _, n := range r.Graph.Nodes {
+		if keep[n.Data.ID] {
+			nodes = append(nodes, n)
+		}
+	}
+
+	edges := make([]Edge, 0, len(r.Graph.Edges))
+	for _, e := range r.Graph.Edges {
+		if keep[e.Data.Source] && keep[e.Data.Target] {
+			edges = append(edges, e)
+		}
+	}
+
+	r.Graph.Nodes = nodes
+	r.Graph.Edges = edges
+	// The kept nodes can still be in a cycle without some of its members,
+	// and bridged edges can make new ones
+	markCycles(&r.Graph)
+
+	pruneResources(r.Map.Root, keep)
+}
+
+// pruneResources removes the resources that aren't kept and reports whether
+// any remain
+func pruneResources(resources map[string]*Resource, keep map[string]bool) bool {
+	for id, re := range resources {
+		kept := keep[id]
+
+		if re.Type == ResourceTypeFile || re.Type == ResourceTypeModule {
+			// Files are keyed by name, so they're kept if any of their resources are
+			if pruneResources(re.Children, keep) {
+				kept = true
+			}
+		} else if kept {
+			pruneResources(re.Children, keep)
+		}
+
+		if !kept {
+			delete(resources, id)
+		}
+	}
+
+	return len(resources) > 0
+}
+
+// WithoutNoOp returns a copy of r whose graph and map don't contain no-op
+// resources. Edges through removed resources are replaced by edges between
+// the resources they connected.
+func (r *Rover) WithoutNoOp() *Rover {
+	h := *r
+	h.Graph = Graph{
+		Nodes: append([]Node(nil), r.Graph.Nodes...),
+		Edges: append([]Edge(nil), r.Graph.Edges...),
+	}
+	h.Map = copyMap(r.Map)
+
+	children, _ := graphTree(r.Graph)
+
+	removed := make(map[string]bool)
+	for _, n := range r.Graph.Nodes {
+		// Drifted resources are kept, they changed even if the plan doesn't change them
+		if n.Data.Change == string(ActionNoop) && !n.Data.Drift {
+			removed[n.Data.ID] = true
+		}
+	}
+	// Resources w/ count or for_each are removed if all their instances are
+	for _, n := range r.Graph.Nodes {
+		if n.Data.Type != ResourceTypeResource && n.Data.Type != ResourceTypeData {
+			continue
+		}
+		instances := children[n.Data.ID]
+		if len(instances) == 0 || n.Data.Change != "" {
+			continue
+		}
+		all := true
+		for _, c := range instances {
+			all = all && removed[c.Data.ID]
+		}
+		if all {
+			removed[n.Data.ID] = true
+		}
+	}
+
+	keep := make(map[string]bool)
+	for _, n := range r.Graph.Nodes {
+		if len(children[n.Data.ID]) == 0 && !removed[n.Data.ID] {
+			keep[n.Data.ID] = true
+		}
+	}
+
+	h.Graph.Edges = append(h.Graph.Edges, bridgeEdges(r.Graph, removed)...)
+	h.pruneAssets(keep)
+
+	return &h
+}
+
+// bridgeEdges returns edges connecting the dependents of removed nodes to
+// their nearest dependencies that aren't removed, so pruning doesn't hide
+// indirect dependencies
+func bridgeEdges(g Graph, removed map[string]bool) []Edge {
+	types := make(map[string]ResourceType)
+	for _, n := range g.Nodes {
+		types[n.Data.ID] = n.Data.Type
+	}
+
+	deps := make(map[string][]string)
+	exists := make(map[string]bool)
+	for _, e := range g.Edges {
+		deps[e.Data.Source] = append(deps[e.Data.Source], e.Data.Target)
+		exists[e.Data.ID] = true
+	}
+
+	var edges []Edge
+	for _, e := range g.Edges {
+		if removed[e.Data.Source] || !removed[e.Data.Target] {
+			continue
+		}
+
+		visited := make(map[string]bool)
+		stack := []string{e.Data.Target}
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+
+			for _, d := range deps[id] {
+				if removed[d] {
+					stack = append(stack, d)
+					continue
+				}
+
+				edgeId := fmt.Sprintf("%s->%s", e.Data.Source, d)
+				if d == e.Data.Source || exists[edgeId] {
+					continue
+				}
+				exists[edgeId] = true
+				edges = append(edges, Edge{
+					Data: EdgeData{
+						ID:       edgeId,
+						Source:   e.Data.Source,
+						Target:   d,
+						Gradient: fmt.Sprintf("%s %s", getResourceColor(types[e.Data.Source]), getResourceColor(types[d])),
+						Kind:     e.Data.Kind,
+					},
+					Classes: "edge",
+				})
+			}
+		}
+	}
+
+	return edges
+}
+
+// copyMap returns a deep copy of m so it can be pruned
+func copyMap(m *Map) *Map {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.Root = copyResources(m.Root)
+	return &c
+}
+
+func copyResources(resources map[string]*Resource) map[string]*Resource {
+	if resources == nil {
+		return nil
+	}
+	c := make(map[string]*Resource, len(resources))
+	for id, re := range resources {
+		rc := *re
+		rc.Children = copyResources(re.Children)
+		c[id] = &rc
+	}
+	return c
+}
+
+// ChangedPlan returns a copy of the plan whose resource changes only include
+// resources with actions other than no-op
+func (r *Rover) ChangedPlan() *tfjson.Plan {
+	if r.Plan == nil {
+		return nil
+	}
+
+	p := *r.Plan
+	p.ResourceChanges = nil
+	for _, rc := range r.Plan.ResourceChanges {
+		if rc.Change != nil && !rc.Change.Actions.NoOp() {
+			p.ResourceChanges = append(p.ResourceChanges, rc)
+		}
+	}
+
+	return &p
+}
@@ -0,0 +1,101 @@
+package rover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checkCycles checks g's cycles and that exactly the edges within them are
+// highlighted
+func checkCycles(t *testing.T, g Graph, want [][]string) {
+	t.Helper()
+
+	if !reflect.DeepEqual(g.Cycles, want) {
+		t.Errorf("cycles = %v, want %v", g.Cycles, want)
+	}
+
+	cycle := make(map[string]int)
+	for i, c := range want {
+		for _, id := range c {
+			cycle[id] = i + 1
+		}
+	}
+	for _, e := range g.Edges {
+		c := cycle[e.Data.Source]
+		wantCyclic := c != 0 && c == cycle[e.Data.Target]
+		if cyclic := strings.Contains(" "+e.Classes+" ", " cycle "); cyclic != wantCyclic {
+			t.Errorf("edge %s has classes %q, want cycle highlighted %t", e.Data.ID, e.Classes, wantCyclic)
+		}
+	}
+}
+
+// TestFilterCycles checks a cycle that loses a member to -filter is still
+// found between the remaining nodes
+func TestFilterCycles(t *testing.T) {
+	r := New(Config{Filters: []string{"a"}})
+	r.Graph = testGraph([]string{"a->b", "b->a", "b->c", "c->b"})
+	r.Map = &Map{}
+	markCycles(&r.Graph)
+	checkCycles(t, r.Graph, [][]string{{"a", "b", "c"}})
+
+	r.filterAssets()
+
+	checkCycles(t, r.Graph, [][]string{{"a", "b"}})
+	if c := r.GenerateComplexity(); c.Edges != 2 {
+		t.Errorf("complexity edges = %d, want 2", c.Edges)
+	}
+}
+
+// TestWithoutNoOpCycles checks edges bridged over no-op resources can form
+// a cycle
+func TestWithoutNoOpCycles(t *testing.T) {
+	r := New(Config{})
+	// x and y are no-ops, so a->x->b->y->a becomes a<->b
+	r.Graph = testGraph([]string{"a->x", "x->b", "b->y", "y->a", "a->c"})
+	for i, n := range r.Graph.Nodes {
+		r.Graph.Nodes[i].Data.Type = ResourceTypeResource
+		r.Graph.Nodes[i].Data.Change = string(ActionUpdate)
+		if n.Data.ID == "x" || n.Data.ID == "y" {
+			r.Graph.Nodes[i].Data.Change = string(ActionNoop)
+		}
+	}
+	r.Map = &Map{}
+	markCycles(&r.Graph)
+
+	h := r.WithoutNoOp()
+
+	checkCycles(t, h.Graph, [][]string{{"a", "b"}})
+	// r is left unchanged
+	checkCycles(t, r.Graph, [][]string{{"a", "b", "x", "y"}})
+}
+
+// TestFilterTypesCycles checks a cycle through an excluded type is found
+// between the resources bridged around it
+func TestFilterTypesCycles(t *testing.T) {
+	r := New(Config{ExcludeTypes: []string{"aws_iam_*"}})
+	r.Graph = testGraph([]string{
+		"aws_instance.a->aws_iam_role.x",
+		"aws_iam_role.x->aws_instance.b",
+		"aws_instance.b->aws_iam_policy.y",
+		"aws_iam_policy.y->aws_instance.a",
+		"aws_instance.a->aws_s3_bucket.c",
+	})
+	for i := range r.Graph.Nodes {
+		r.Graph.Nodes[i].Data.Type = ResourceTypeResource
+	}
+	r.Map = &Map{}
+	markCycles(&r.Graph)
+
+	r.filterTypes()
+
+	checkCycles(t, r.Graph, [][]string{{"aws_instance.a", "aws_instance.b"}})
+	for _, n := range r.Graph.Nodes {
+		if strings.HasPrefix(n.Data.ID, "aws_iam_") {
+			t.Errorf("excluded %s is in the graph", n.Data.ID)
+		}
+	}
+	if c := r.GenerateComplexity(); c.LongestChain != 1 {
+		t.Errorf("longest chain = %d %v, want aws_instance.a -> aws_s3_bucket.c", c.LongestChain, c.LongestChainPath)
+	}
+}
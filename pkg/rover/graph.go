@@ -1,8 +1,8 @@
-package main
+package rover
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 	"strings"
 
@@ -24,6 +24,8 @@ const (
 type Graph struct {
 	Nodes []Node `json:"nodes"`
 	Edges []Edge `json:"edges"`
+	// Addresses of resources that depend on each other
+	Cycles [][]string `json:"cycles,omitempty"`
 }
 
 // Node TODO
@@ -40,6 +42,13 @@ type NodeData struct {
 	Parent      string       `json:"parent,omitempty"`
 	ParentColor string       `json:"parentColor,omitempty"`
 	Change      string       `json:"change,omitempty"`
+	Diff        string       `json:"diff,omitempty"`
+	Drift       bool         `json:"drift,omitempty"`
+	// Address a moved resource is moved from
+	PreviousAddress string `json:"previous_address,omitempty"`
+	// Resource type groups collapsed by -collapseThreshold
+	Collapsed  bool `json:"collapsed,omitempty"`
+	ChildCount int  `json:"child_count,omitempty"`
 }
 
 // Edge TODO
@@ -54,11 +63,25 @@ type EdgeData struct {
 	Source   string `json:"source"`
 	Target   string `json:"target"`
 	Gradient string `json:"gradient,omitempty"`
+	// Why the source depends on the target
+	Kind EdgeKind `json:"kind,omitempty"`
 }
 
+// EdgeKind is the kind of dependency an edge represents
+type EdgeKind string
+
+const (
+	// An expression references the target
+	EdgeKindReference EdgeKind = "reference"
+	// The target is listed in depends_on
+	EdgeKindDependsOn EdgeKind = "depends_on"
+	// A module call passes the target to the module as an input
+	EdgeKindModuleCall EdgeKind = "module_call"
+)
+
 // GenerateGraph -
-func (r *rover) GenerateGraph() error {
-	log.Println("Generating resource graph...")
+func (r *Rover) GenerateGraph() error {
+	slog.Debug("Generating resource graph...")
 
 	nodes := r.GenerateNodes()
 	edges := r.GenerateEdges()
@@ -80,15 +103,26 @@ func (r *rover) GenerateGraph() error {
 		}
 	}
 
+	// Moved resources are a single node at their new address
+	for i, n := range nodes {
+		if so, ok := r.RSO.States[n.Data.ID]; ok && so.PreviousAddress != "" {
+			nodes[i].Data.PreviousAddress = so.PreviousAddress
+			nodes[i].Classes = fmt.Sprintf("%s moved", n.Classes)
+		}
+	}
+
 	r.Graph = Graph{
 		Nodes: nodes,
 		Edges: edges,
 	}
 
+	markCycles(&r.Graph)
+	warnCycles(r.Graph.Cycles)
+
 	return nil
 }
 
-func (r *rover) addNodes(base string, parent string, nodeMap map[string]Node, resources map[string]*Resource) []string {
+func (r *Rover) addNodes(base string, parent string, nodeMap map[string]Node, resources map[string]*Resource) []string {
 
 	nmo := []string{}
 
@@ -187,6 +221,11 @@ func (r *rover) addNodes(base string, parent string, nodeMap map[string]Node, re
 			ls := strings.Split(id, ".")
 			label := ls[len(ls)-1]
 
+			// Modules deeper than -maxDepth
+			if re.Collapsed {
+				label = fmt.Sprintf("%s (%d resources)", label, re.ChildCount)
+			}
+
 			//fmt.Printf("%v - %v\n", id, re.Type)
 
 			nmo = append(nmo, id)
@@ -213,7 +252,7 @@ func (r *rover) addNodes(base string, parent string, nodeMap map[string]Node, re
 }
 
 // GenerateNodes -
-func (r *rover) GenerateNodes() []Node {
+func (r *Rover) GenerateNodes() []Node {
 
 	nodeMap := make(map[string]Node)
 	nmo := []string{}
@@ -245,7 +284,7 @@ func (r *rover) GenerateNodes() []Node {
 	return nodes
 }
 
-func (r *rover) addEdges(base string, parent string, edgeMap map[string]Edge, resources map[string]*Resource) []string {
+func (r *Rover) addEdges(base string, parent string, edgeMap map[string]Edge, resources map[string]*Resource) []string {
 	emo := []string{}
 	for id, re := range resources {
 		matchBrackets := regexp.MustCompile(`\[[^\[\]]*\]`)
@@ -253,69 +292,106 @@ func (r *rover) addEdges(base string, parent string, edgeMap map[string]Edge, re
 		configId := matchBrackets.ReplaceAllString(id, "")
 
 		var expressions map[string]*tfjson.Expression
+		var dependsOn []string
+		kind := EdgeKindReference
 
 		if r.RSO.Configs[configId] != nil {
 			// If Resource
 			if r.RSO.Configs[configId].ResourceConfig != nil {
 				expressions = r.RSO.Configs[configId].ResourceConfig.Expressions
+				dependsOn = r.RSO.Configs[configId].ResourceConfig.DependsOn
 				// If Module
 			} else if r.RSO.Configs[configId].ModuleConfig != nil {
 				expressions = r.RSO.Configs[configId].ModuleConfig.Expressions
+				dependsOn = r.RSO.Configs[configId].ModuleConfig.DependsOn
+				kind = EdgeKindModuleCall
 				// If Output
 			} else if r.RSO.Configs[configId].OutputConfig != nil {
 				expressions = make(map[string]*tfjson.Expression)
 				expressions["output"] = r.RSO.Configs[configId].OutputConfig.Expression
+				dependsOn = r.RSO.Configs[configId].OutputConfig.DependsOn
 			}
 		}
-		// fmt.Printf("%+v - %+v\n", oName, oValue)
+
+		// Locals aren't in the plan's configuration
+		if re.Type == ResourceTypeLocal && len(re.References) > 0 {
+			expressions = map[string]*tfjson.Expression{
+				"local": {ExpressionData: &tfjson.ExpressionData{References: re.References}},
+			}
+		}
+
+		type edgeRef struct {
+			ref  string
+			kind EdgeKind
+		}
+		var refs []edgeRef
 		for _, reValues := range expressions {
-			for _, dependsOnR := range reValues.References {
-				if !strings.HasPrefix(dependsOnR, "each.") {
-
-					/*if strings.HasPrefix(dependsOnR, "module.") {
-						id := strings.Split(dependsOnR, ".")
-						dependsOnR = fmt.Sprintf("%s.%s", id[0], id[1])
-					}*/
-
-					sourceColor := getResourceColor(re.Type)
-					targetId := dependsOnR
-					if parent != "" {
-						targetId = fmt.Sprintf("%s.%s", parent, dependsOnR)
-					}
-
-					targetColor := RESOURCE_COLOR
-
-					if strings.Contains(dependsOnR, "output.") {
-						targetColor = OUTPUT_COLOR
-					} else if strings.Contains(dependsOnR, "var.") {
-						targetColor = VARIABLE_COLOR
-					} else if strings.HasPrefix(dependsOnR, "module.") {
-						targetColor = MODULE_COLOR
-					} else if strings.Contains(dependsOnR, "data.") {
-						targetColor = DATA_COLOR
-					} else if strings.Contains(dependsOnR, "local.") {
-						targetColor = LOCAL_COLOR
-					}
-
-					// For Terraform 1.0, resource references point to specific resource attributes
-					// Skip if the target is a resource and reference points to an attribute
-					if targetColor == RESOURCE_COLOR && len(strings.Split(dependsOnR, ".")) != 2 {
-						continue
-					} else if targetColor == DATA_COLOR && len(strings.Split(dependsOnR, ".")) != 3 {
-						continue
-					}
-
-					edgeId := fmt.Sprintf("%s->%s", id, targetId)
-					emo = append(emo, edgeId)
-					edgeMap[edgeId] = Edge{
-						Data: EdgeData{
-							ID:       edgeId,
-							Source:   id,
-							Target:   targetId,
-							Gradient: fmt.Sprintf("%s %s", sourceColor, targetColor),
-						},
-						Classes: "edge",
-					}
+			if reValues == nil || reValues.ExpressionData == nil {
+				continue
+			}
+			for _, ref := range reValues.References {
+				refs = append(refs, edgeRef{ref, kind})
+			}
+		}
+		// Explicit dependencies come last so they take precedence over references
+		for _, ref := range dependsOn {
+			refs = append(refs, edgeRef{ref, EdgeKindDependsOn})
+		}
+
+		// fmt.Printf("%+v - %+v\n", oName, oValue)
+		for _, er := range refs {
+			dependsOnR := er.ref
+			if !strings.HasPrefix(dependsOnR, "each.") {
+
+				/*if strings.HasPrefix(dependsOnR, "module.") {
+					id := strings.Split(dependsOnR, ".")
+					dependsOnR = fmt.Sprintf("%s.%s", id[0], id[1])
+				}*/
+
+				sourceColor := getResourceColor(re.Type)
+				targetId := dependsOnR
+				if parent != "" {
+					targetId = fmt.Sprintf("%s.%s", parent, dependsOnR)
+				}
+
+				targetColor := RESOURCE_COLOR
+
+				if strings.Contains(dependsOnR, "output.") {
+					targetColor = OUTPUT_COLOR
+				} else if strings.Contains(dependsOnR, "var.") {
+					targetColor = VARIABLE_COLOR
+				} else if strings.HasPrefix(dependsOnR, "module.") {
+					targetColor = MODULE_COLOR
+				} else if strings.Contains(dependsOnR, "data.") {
+					targetColor = DATA_COLOR
+				} else if strings.Contains(dependsOnR, "local.") {
+					targetColor = LOCAL_COLOR
+				}
+
+				// For Terraform 1.0, resource references point to specific resource attributes
+				// Skip if the target is a resource and reference points to an attribute
+				if targetColor == RESOURCE_COLOR && len(strings.Split(dependsOnR, ".")) != 2 {
+					continue
+				} else if targetColor == DATA_COLOR && len(strings.Split(dependsOnR, ".")) != 3 {
+					continue
+				}
+
+				// Instances aren't nodes when collapsed, point to their resource instead
+				if r.CollapseInstances {
+					targetId = regexp.MustCompile(`\[[^[\]]*\]$`).ReplaceAllString(targetId, "")
+				}
+
+				edgeId := fmt.Sprintf("%s->%s", id, targetId)
+				emo = append(emo, edgeId)
+				edgeMap[edgeId] = Edge{
+					Data: EdgeData{
+						ID:       edgeId,
+						Source:   id,
+						Target:   targetId,
+						Gradient: fmt.Sprintf("%s %s", sourceColor, targetColor),
+						Kind:     er.kind,
+					},
+					Classes: "edge",
 				}
 			}
 		}
@@ -332,7 +408,7 @@ func (r *rover) addEdges(base string, parent string, edgeMap map[string]Edge, re
 }
 
 // GenerateEdges -
-func (r *rover) GenerateEdges() []Edge {
+func (r *Rover) GenerateEdges() []Edge {
 	edgeMap := make(map[string]Edge)
 	emo := []string{}
 
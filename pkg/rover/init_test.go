@@ -0,0 +1,92 @@
+package rover
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeTerraform writes a terraform script that reports version 1.6.0 and
+// runs the given shell commands for each subcommand, other subcommands
+// succeed
+func fakeTerraform(t *testing.T, commands map[string]string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake terraform is a shell script")
+	}
+
+	script := `#!/bin/sh
+case "$1" in
+  version) echo '{"terraform_version":"1.6.0","platform":"linux_amd64","provider_selections":{},"terraform_outdated":false}';;
+`
+	for subcommand, run := range commands {
+		script += "  " + subcommand + ") " + run + ";;\n"
+	}
+	script += `  *) exit 0;;
+esac
+`
+	path := filepath.Join(t.TempDir(), "terraform")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInitError(t *testing.T) {
+	tests := []struct {
+		name string
+		init string
+		want []string
+	}{
+		{
+			name: "failure",
+			init: `echo "Initializing the backend..."; echo "Error: Failed to get existing workspaces: bucket does not exist" >&2; exit 1`,
+			want: []string{
+				"Unable to initialize Terraform Plan",
+				"Error: Failed to get existing workspaces: bucket does not exist",
+				// stdout isn't part of tfexec's error
+				"Last Terraform output:\nInitializing the backend...",
+			},
+		},
+		{
+			name: "input required",
+			init: `echo "Error: No value for required variable" >&2; exit 1`,
+			want: []string{
+				"Terraform init needs interactive input, which Rover disables",
+				"Error: No value for required variable",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte("resource \"null_resource\" \"a\" {}\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			r := New(Config{
+				Name:       "rover",
+				WorkingDir: dir,
+				TfPath:     fakeTerraform(t, map[string]string{"init": tt.init}),
+			})
+			err := r.Generate(context.Background())
+			if err == nil {
+				t.Fatal("Generate succeeded, want init error")
+			}
+
+			// PlanErrors exit with code 2
+			if !errors.As(err, &PlanError{}) {
+				t.Errorf("error is %T, want PlanError", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error doesn't contain %q:\n%s", w, err)
+				}
+			}
+		})
+	}
+}
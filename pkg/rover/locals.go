@@ -0,0 +1,107 @@
+package rover
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Local is a local value declared in a module's locals blocks
+type Local struct {
+	Name       string
+	Filename   string
+	Line       int
+	References []string
+}
+
+// loadLocals parses the local values declared in the module's *.tf files.
+// tfconfig doesn't read locals, files that fail to parse are skipped.
+func loadLocals(dir string) map[string]*Local {
+	locals := make(map[string]*Local)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return locals
+	}
+
+	parser := hclparse.NewParser()
+	for _, fname := range files {
+		f, diags := parser.ParseHCLFile(fname)
+		if diags.HasErrors() {
+			continue
+		}
+
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type != "locals" {
+				continue
+			}
+
+			for name, attr := range block.Body.Attributes {
+				locals[name] = &Local{
+					Name:       name,
+					Filename:   fname,
+					Line:       attr.SrcRange.Start.Line,
+					References: expressionReferences(attr.Expr),
+				}
+			}
+		}
+	}
+
+	return locals
+}
+
+// expressionReferences returns the objects the expression references, in the
+// format Terraform uses for references in the plan's configuration
+func expressionReferences(expr hclsyntax.Expression) []string {
+	refs := make(map[string]bool)
+
+	for _, t := range expr.Variables() {
+		attrs := traversalAttrs(t)
+
+		switch t.RootName() {
+		case "var", "local", "module":
+			if len(attrs) > 0 {
+				refs[fmt.Sprintf("%s.%s", t.RootName(), attrs[0])] = true
+			}
+		case "data":
+			if len(attrs) > 1 {
+				refs[fmt.Sprintf("data.%s.%s", attrs[0], attrs[1])] = true
+			}
+		case "count", "each", "path", "self", "terraform":
+		default:
+			if len(attrs) > 0 {
+				refs[fmt.Sprintf("%s.%s", t.RootName(), attrs[0])] = true
+			}
+		}
+	}
+
+	references := make([]string, 0, len(refs))
+	for ref := range refs {
+		references = append(references, ref)
+	}
+	sort.Strings(references)
+
+	return references
+}
+
+// traversalAttrs returns the attribute names following the traversal's root
+func traversalAttrs(t hcl.Traversal) []string {
+	var attrs []string
+	for _, step := range t[1:] {
+		attr, ok := step.(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+		attrs = append(attrs, attr.Name)
+	}
+	return attrs
+}
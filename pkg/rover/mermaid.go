@@ -0,0 +1,127 @@
+package rover
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateMermaid renders the graph as a Mermaid flowchart. Nodes with
+// children (modules, files and resource types) are rendered as subgraphs.
+func (r *Rover) GenerateMermaid() []byte {
+	var b bytes.Buffer
+
+	children, roots := graphTree(r.Graph)
+	ids := mermaidIDs(r.Graph)
+	changed := make(map[string][]string)
+	var drifted []string
+
+	b.WriteString("graph TD\n")
+
+	var writeNode func(n Node, indent string)
+	writeNode = func(n Node, indent string) {
+		id := ids[n.Data.ID]
+
+		if len(children[n.Data.ID]) == 0 {
+			// Data sources are read, not changed
+			if n.Data.Type == ResourceTypeData {
+				fmt.Fprintf(&b, "%s%s([%s])\n", indent, id, mermaidQuote(n.Data.ID))
+			} else {
+				fmt.Fprintf(&b, "%s%s[%s]\n", indent, id, mermaidQuote(n.Data.ID))
+			}
+			if _, ok := changeColors[n.Data.Change]; ok {
+				changed[n.Data.Change] = append(changed[n.Data.Change], id)
+			}
+			if n.Data.Drift {
+				drifted = append(drifted, id)
+			}
+			return
+		}
+
+		fmt.Fprintf(&b, "%ssubgraph %s [%s]\n", indent, id, mermaidQuote(n.Data.Label))
+		for _, c := range children[n.Data.ID] {
+			writeNode(c, indent+"  ")
+		}
+		b.WriteString(indent + "end\n")
+	}
+
+	for _, n := range roots {
+		writeNode(n, "  ")
+	}
+
+	cyclic := cyclicEdges(r.Graph)
+	var cyclicLinks []string
+	for i, e := range graphEdges(r.Graph) {
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[e.Data.Source], mermaidArrow(e.Data.Kind), ids[e.Data.Target])
+		if cyclic[e.Data.ID] {
+			cyclicLinks = append(cyclicLinks, strconv.Itoa(i))
+		}
+	}
+
+	if len(cyclicLinks) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:red,stroke-width:2px\n", strings.Join(cyclicLinks, ","))
+	}
+
+	// Color-code changes
+	actions := make([]string, 0, len(changed))
+	for action := range changed {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	for _, action := range actions {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", action, changeColors[action])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(changed[action], ","), action)
+	}
+
+	if len(drifted) > 0 {
+		fmt.Fprintf(&b, "  classDef drift stroke:%s,stroke-width:3px,stroke-dasharray:5 3\n", driftStroke)
+		fmt.Fprintf(&b, "  class %s drift\n", strings.Join(drifted, ","))
+	}
+
+	return b.Bytes()
+}
+
+// mermaidIDs maps node IDs to IDs Mermaid accepts. Resource addresses contain
+// dots and brackets, which Mermaid doesn't allow in IDs.
+func mermaidIDs(g Graph) map[string]string {
+	invalid := regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+	ids := make(map[string]string, len(g.Nodes))
+	used := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		id := "n_" + invalid.ReplaceAllString(n.Data.ID, "_")
+
+		// Different addresses can sanitize to the same ID
+		unique := id
+		for i := 1; used[unique]; i++ {
+			unique = fmt.Sprintf("%s_%d", id, i)
+		}
+
+		used[unique] = true
+		ids[n.Data.ID] = unique
+	}
+
+	return ids
+}
+
+// mermaidArrow returns the link for an edge: dotted for depends_on and thick
+// for module inputs
+func mermaidArrow(kind EdgeKind) string {
+	switch kind {
+	case EdgeKindDependsOn:
+		return "-.->"
+	case EdgeKindModuleCall:
+		return "==>"
+	default:
+		return "-->"
+	}
+}
+
+// mermaidQuote returns s as a quoted Mermaid label
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
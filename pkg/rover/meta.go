@@ -0,0 +1,66 @@
+package rover
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// Meta describes the context the assets were generated in
+type Meta struct {
+	Name      string   `json:"name"`
+	Workspace string   `json:"workspace,omitempty"`
+	Destroy   bool     `json:"destroy,omitempty"`
+	Targets   []string `json:"targets,omitempty"`
+	Filters   []string `json:"filters,omitempty"`
+	MaxDepth  int      `json:"max_depth,omitempty"`
+	// Whether the plan changes any resources, unset for state
+	HasChanges *bool       `json:"hasChanges,omitempty"`
+	Complexity *Complexity `json:"complexity,omitempty"`
+	// Terraform or OpenTofu, only set when Rover runs the binary
+	Product          string            `json:"product,omitempty"`
+	TfPath           string            `json:"tf_path,omitempty"`
+	TerraformVersion string            `json:"terraform_version,omitempty"`
+	ProviderVersions map[string]string `json:"provider_versions,omitempty"`
+	WorkingDir       string            `json:"working_dir"`
+	GeneratedAt      time.Time         `json:"generated_at"`
+}
+
+// GenerateMeta - Information about the Rover run
+func (r *Rover) GenerateMeta() error {
+	slog.Debug("Generating meta...")
+
+	workingDir, err := filepath.Abs(r.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	r.Meta = &Meta{
+		Name:             r.Name,
+		Workspace:        r.WorkspaceName,
+		Destroy:          r.Destroy,
+		Targets:          r.Targets,
+		Filters:          r.Filters,
+		MaxDepth:         r.MaxDepth,
+		ProviderVersions: r.ProviderVersions,
+		WorkingDir:       workingDir,
+		GeneratedAt:      time.Now().UTC(),
+		Complexity:       r.GenerateComplexity(),
+	}
+
+	if !r.FromState && r.RSO != nil && r.RSO.Summary != nil {
+		hasChanges := r.RSO.Summary.HasChanges()
+		r.Meta.HasChanges = &hasChanges
+	}
+
+	if r.TfVersion != "" {
+		r.Meta.Product = tfProduct(r.TfPath)
+		r.Meta.TfPath = r.TfPath
+		r.Meta.TerraformVersion = r.TfVersion
+	} else if r.Plan != nil {
+		// Version that generated the provided plan
+		r.Meta.TerraformVersion = r.Plan.TerraformVersion
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package rover
+
+import (
+	"sort"
+)
+
+// Order is the sequence resources are applied in. Resources in the same wave
+// don't depend on each other and can be applied in parallel.
+type Order struct {
+	Waves [][]OrderedResource `json:"waves"`
+	// Dependency cycles that prevent ordering. Resources in or depending on a
+	// cycle are left out of the waves.
+	Cycles [][]string `json:"cycles,omitempty"`
+}
+
+// OrderedResource is a resource in an apply wave
+type OrderedResource struct {
+	Address string       `json:"address"`
+	Type    ResourceType `json:"type"`
+	Change  string       `json:"change,omitempty"`
+}
+
+// Order sorts the graph's resources topologically into waves. Dependencies
+// through variables, locals, outputs and module calls are followed, so a
+// resource is applied after every resource it depends on indirectly.
+func (r *Rover) Order() Order {
+	// Collapsed groups hide resources, so order the full graph
+	g := r.fullGraph
+	if len(g.Nodes) == 0 {
+		g = r.Graph
+	}
+
+	nodes := make(map[string]Node, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes[n.Data.ID] = n
+	}
+
+	// Resources with count or for_each are ordered as a whole, their
+	// instances' nodes don't have their dependencies
+	resources := resourceNodes(g, r.RSO)
+	isResource := func(id string) bool {
+		return resources[id]
+	}
+
+	deps := make(map[string][]string)
+	for _, e := range graphEdges(g) {
+		deps[e.Data.Source] = append(deps[e.Data.Source], e.Data.Target)
+	}
+	// Module variables are set by the module call
+	for _, n := range g.Nodes {
+		if n.Data.Type == ResourceTypeVariable {
+			if p, ok := nodes[n.Data.Parent]; ok && p.Data.Type == ResourceTypeModule {
+				deps[n.Data.ID] = append(deps[n.Data.ID], p.Data.ID)
+			}
+		}
+	}
+
+	order := Order{
+		Waves:  [][]OrderedResource{},
+		Cycles: findCycles(Graph{Nodes: g.Nodes, Edges: edgesFrom(deps)}),
+	}
+	cyclic := make(map[string]bool)
+	for _, c := range order.Cycles {
+		for _, id := range c {
+			cyclic[id] = true
+		}
+	}
+
+	// wave is the number of resources on the longest dependency chain
+	// before id, -1 if it depends on a cycle
+	waves := make(map[string]int)
+	var wave func(id string) int
+	wave = func(id string) int {
+		if w, ok := waves[id]; ok {
+			return w
+		}
+		if cyclic[id] {
+			waves[id] = -1
+			return -1
+		}
+		w := 0
+		for _, d := range deps[id] {
+			if d == id {
+				continue
+			}
+			dw := wave(d)
+			if dw < 0 {
+				w = -1
+				break
+			}
+			if isResource(d) {
+				dw++
+			}
+			if dw > w {
+				w = dw
+			}
+		}
+
+		waves[id] = w
+		return w
+	}
+
+	for _, n := range g.Nodes {
+		id := n.Data.ID
+		if !isResource(id) {
+			continue
+		}
+		w := wave(id)
+		if w < 0 {
+			continue
+		}
+		for len(order.Waves) <= w {
+			order.Waves = append(order.Waves, []OrderedResource{})
+		}
+		order.Waves[w] = append(order.Waves[w], OrderedResource{
+			Address: id,
+			Type:    n.Data.Type,
+			Change:  n.Data.Change,
+		})
+	}
+
+	for _, w := range order.Waves {
+		sort.Slice(w, func(i, j int) bool {
+			return w[i].Address < w[j].Address
+		})
+	}
+
+	return order
+}
+
+// edgesFrom returns the edges of a dependency map
+func edgesFrom(deps map[string][]string) []Edge {
+	var edges []Edge
+	for source, targets := range deps {
+		for _, target := range targets {
+			edges = append(edges, Edge{Data: EdgeData{
+				ID:     source + "->" + target,
+				Source: source,
+				Target: target,
+			}})
+		}
+	}
+	return edges
+}
@@ -0,0 +1,79 @@
+package rover
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Lines of Terraform output kept to explain failures
+const outputTailLines = 20
+
+// terraformOutput logs Terraform's stdout and stderr at debug level and keeps
+// the last lines of both, so failures show what Terraform printed
+type terraformOutput struct {
+	mu      sync.Mutex
+	tail    []string
+	writers []*outputWriter
+}
+
+// outputWriter splits one of Terraform's output streams into lines
+type outputWriter struct {
+	output  *terraformOutput
+	stream  string
+	partial []byte
+}
+
+// writer returns a writer for one of Terraform's output streams
+func (o *terraformOutput) writer(stream string) io.Writer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	w := &outputWriter{output: o, stream: stream}
+	o.writers = append(o.writers, w)
+	return w
+}
+
+func (w *outputWriter) Write(b []byte) (int, error) {
+	o := w.output
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	w.partial = append(w.partial, b...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.partial[:i]), "\r")
+		w.partial = w.partial[i+1:]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		slog.Debug("Terraform output", "stream", w.stream, "line", line)
+		o.tail = append(o.tail, line)
+		if len(o.tail) > outputTailLines {
+			o.tail = o.tail[len(o.tail)-outputTailLines:]
+		}
+	}
+
+	return len(b), nil
+}
+
+// Lines returns the last lines of output, including unfinished lines like
+// prompts
+func (o *terraformOutput) Lines() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	lines := append([]string(nil), o.tail...)
+	for _, w := range o.writers {
+		if len(bytes.TrimSpace(w.partial)) > 0 {
+			lines = append(lines, strings.TrimSpace(string(w.partial)))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,87 @@
+package rover
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// ProviderRequirement is a provider a module requires, from its
+// required_providers block or, if undeclared, its resources and provider blocks
+type ProviderRequirement struct {
+	Name string `json:"name"`
+	// Address of the module, empty for the root module
+	Module             string   `json:"module"`
+	Source             string   `json:"source,omitempty"`
+	VersionConstraints []string `json:"version_constraints,omitempty"`
+	// Version selected by init, only set when Rover runs the binary
+	Version string `json:"version,omitempty"`
+	// Set if the provider is declared in required_providers
+	Declared bool `json:"declared"`
+}
+
+// GenerateProviders lists the providers each module loaded from the
+// filesystem requires, with the versions selected by init. Providers used
+// but missing from required_providers are included as undeclared.
+func (r *Rover) GenerateProviders() {
+	slog.Debug("Generating providers...")
+
+	modules := make([]string, 0, len(r.RSO.Configs))
+	for address, c := range r.RSO.Configs {
+		if c.Module != nil {
+			modules = append(modules, address)
+		}
+	}
+	sort.Strings(modules)
+
+	providers := []ProviderRequirement{}
+	for _, address := range modules {
+		module := r.RSO.Configs[address].Module
+
+		names := make([]string, 0, len(module.RequiredProviders))
+		for name := range module.RequiredProviders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			req := module.RequiredProviders[name]
+
+			// The configuration loader adds providers used by resources without a
+			// source or version constraints
+			declared := req.Source != "" || len(req.VersionConstraints) > 0
+			if !declared {
+				slog.Warn("Provider is used but not declared in required_providers", "provider", name, "module", address)
+			}
+
+			source := req.Source
+			if source == "" {
+				source = "hashicorp/" + name
+			}
+
+			providers = append(providers, ProviderRequirement{
+				Name:               name,
+				Module:             address,
+				Source:             source,
+				VersionConstraints: req.VersionConstraints,
+				Version:            r.providerVersion(source),
+				Declared:           declared,
+			})
+		}
+	}
+
+	r.Providers = providers
+}
+
+// providerVersion returns the version init selected for the provider source,
+// which is given without the registry hostname by default
+func (r *Rover) providerVersion(source string) string {
+	source = strings.ToLower(source)
+	for address, v := range r.ProviderVersions {
+		address = strings.ToLower(address)
+		if address == source || strings.HasSuffix(address, "/"+source) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,922 @@
+package rover
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	version "github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Delay before the first plan retry, doubled for each further retry up to
+// maxRetryBackoff
+const retryBackoff = 2 * time.Second
+const maxRetryBackoff = time.Minute
+
+// Terraform errors, and tfexec's rewrite of them, for prompts it couldn't show because
+// input is disabled
+var inputRequired = regexp.MustCompile(`(?i)no value for required variable|was required but not supplied|input is disabled|-input=false|error asking for`)
+
+// Terraform error for plans with -out when the remote or cloud backend runs them
+var remotePlan = regexp.MustCompile(`(?i)saving a generated plan is (currently )?not supported`)
+
+// Plan JSON format versions Rover has been tested against
+const testedPlanFormatVersions = ">= 0.1, < 1.3"
+
+var TRUE = true
+
+// PlanError is returned when Rover is unable to generate or read the plan
+type PlanError struct {
+	err error
+}
+
+func (e PlanError) Error() string {
+	return e.err.Error()
+}
+
+func (e PlanError) Unwrap() error {
+	return e.err
+}
+
+// Config is the configuration Rover generates the assets from
+type Config struct {
+	Name        string
+	WorkingDir  string
+	TfPath      string
+	Timeout     time.Duration
+	LockTimeout time.Duration
+	// Terraform variables and *.tfvars files, in the order they're given so
+	// later ones override earlier ones
+	TfVars           []TfVar
+	TfBackendConfigs []string
+	Targets          []string
+	Filters          []string
+	IncludeTypes     []string
+	ExcludeTypes     []string
+	PlanPath         string
+	PlanJSONPath     string
+	ComparePlanPath  string
+	WorkspaceName    string
+	TFCOrgName       string
+	TFCWorkspaceName string
+	TFCRunID         string
+	// Defaults to the TFC_TOKEN environment variable
+	TFCToken          string
+	ShowSensitive     bool
+	TFCNewRun         bool
+	SkipInit          bool
+	Upgrade           bool
+	Destroy           bool
+	Refresh           bool
+	FromState         bool
+	CollapseInstances bool
+	HideNoOp          bool
+	MaxDepth          int
+	// Number of times to retry plans that fail with transient errors
+	Retries int
+	// Number of concurrent operations during plan, Terraform's default if 0
+	Parallelism int
+	// Directory for the plan file, the OS temporary directory if empty
+	TmpDir string
+	// File to write Terraform's TRACE log to, disabled if empty
+	TfLogPath string
+	// Environment variables for Terraform, overriding Rover's environment
+	Env map[string]string
+	// Fail on plan format versions Rover hasn't been tested against
+	StrictVersion bool
+	// Resource type groups with at least this many resources are collapsed
+	// in the graph, 0 disables collapsing
+	CollapseThreshold int
+	// Replace resource and module names with generic names, and generate a
+	// legend of the original names if AnonymizeLegend is set
+	Anonymize       bool
+	AnonymizeLegend bool
+}
+
+// TfVar is a Terraform variable (name=value) for -var, or a *.tfvars file for
+// -var-file if File is set
+type TfVar struct {
+	Value string
+	File  bool
+}
+
+// Assets are the generated plan, resource overview, map, graph, meta and providers
+type Assets struct {
+	Plan  *tfjson.Plan
+	RSO   *ResourcesOverview
+	Map   *Map
+	Graph Graph
+	Meta  *Meta
+	Diff  *Diff
+	// Providers required by each module
+	Providers []ProviderRequirement
+	// Original names of anonymized resources and modules
+	Legend map[string]string
+}
+
+// Rover generates the assets of a Terraform configuration
+type Rover struct {
+	Config
+	Assets
+
+	// Versions of the binary and providers that generated the plan
+	TfVersion        string
+	ProviderVersions map[string]string
+
+	// Graph before resource groups are collapsed, to expand them on request
+	fullGraph Graph
+	// Terraform's output while generating the plan
+	output *terraformOutput
+}
+
+// New returns a Rover that generates assets from config
+func New(config Config) *Rover {
+	return &Rover{Config: config}
+}
+
+// Generate generates the assets of the configuration in cfg. Canceling ctx
+// stops Terraform and Terraform Cloud calls.
+func Generate(ctx context.Context, cfg Config) (*Assets, error) {
+	r := New(cfg)
+
+	err := r.Generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r.Assets, nil
+}
+
+// Generate gets the plan and generates the resource overview, map, graph and meta
+func (r *Rover) Generate(ctx context.Context) error {
+	// Plans and states are generated in the working directory
+	if r.PlanJSONPath == "" && r.TFCWorkspaceName == "" && r.TFCRunID == "" {
+		err := checkWorkingDir(r.WorkingDir, r.PlanPath == "" && !r.FromState)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Get Plan
+	err := r.GeneratePlan(ctx)
+	if err != nil {
+		return PlanError{errors.New(fmt.Sprintf("Unable to parse Plan: %s", err))}
+	}
+
+	// States have their own format version
+	if !r.FromState {
+		err = r.checkPlanFormatVersion("Plan", r.Plan)
+		if err != nil {
+			return PlanError{err}
+		}
+	}
+
+	if !r.ShowSensitive {
+		redactPlan(r.Plan)
+	}
+
+	// Generate RSO, Map, Graph
+	err = r.GenerateResourceOverview()
+	if err != nil {
+		return err
+	}
+
+	r.GenerateProviders()
+
+	err = r.GenerateMap()
+	if err != nil {
+		return err
+	}
+
+	err = r.GenerateGraph()
+	if err != nil {
+		return err
+	}
+
+	r.GenerateDrift()
+
+	if r.ComparePlanPath != "" {
+		err = r.GenerateDiff(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(r.IncludeTypes) > 0 || len(r.ExcludeTypes) > 0 {
+		r.filterTypes()
+	}
+
+	if len(r.Filters) > 0 {
+		r.filterAssets()
+	}
+
+	r.fullGraph = r.Graph
+	r.Graph = collapseGroups(r.Graph, r.CollapseThreshold, nil)
+
+	err = r.GenerateMeta()
+	if err != nil {
+		return err
+	}
+
+	if r.Anonymize {
+		return r.AnonymizeAssets(r.AnonymizeLegend)
+	}
+
+	return nil
+}
+
+// gunzip decompresses b if it's gzipped, detected by the gzip magic bytes
+func gunzip(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, []byte{0x1f, 0x8b}) {
+		return b, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return io.ReadAll(gz)
+}
+
+// GeneratePlan gets the plan from a plan JSON file, a plan file, Terraform Cloud
+// or by running terraform plan in the working directory. Canceling ctx stops
+// Terraform and Terraform Cloud calls.
+func (r *Rover) GeneratePlan(ctx context.Context) error {
+	// If user provided path to plan JSON file
+	// Terraform binary isn't required to read a JSON plan
+	if r.PlanJSONPath != "" {
+		slog.Debug("Using provided JSON plan...")
+
+		planJsonFile, err := os.Open(r.PlanJSONPath)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanJSONPath, err))
+		}
+		defer planJsonFile.Close()
+
+		planJson, err := io.ReadAll(planJsonFile)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanJSONPath, err))
+		}
+
+		planJson, err = gunzip(planJson)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanJSONPath, err))
+		}
+
+		if err := json.Unmarshal(planJson, &r.Plan); err != nil {
+			return errors.New(fmt.Sprintf("Unable to read Plan (%s): %s", r.PlanJSONPath, err))
+		}
+
+		return nil
+	}
+
+	if r.TFCRunID != "" {
+		return r.generateTFCRunPlan(ctx)
+	}
+
+	// If user specified TFC workspace
+	if r.TFCWorkspaceName != "" {
+		if r.TFCOrgName == "" {
+			return errors.New("Must specify Terraform Cloud organization to retrieve plan from Terraform Cloud")
+		}
+
+		client, err := r.tfcClient()
+		if err != nil {
+			return err
+		}
+
+		// Get TFC Workspace
+		ws, err := client.Workspaces.Read(ctx, r.TFCOrgName, r.TFCWorkspaceName)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to list workspace %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+		}
+
+		// Retrieve all runs from specified TFC workspace
+		runs, err := client.Runs.List(ctx, ws.ID, tfe.RunListOptions{})
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to retrieve plan from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+		}
+
+		run := runs.Items[0]
+
+		// Get most recent plan item
+		planID := runs.Items[0].Plan.ID
+
+		// Run hasn't been applied or discarded, therefore is still "actionable" by user
+		runIsActionable := run.StatusTimestamps.AppliedAt.IsZero() && run.StatusTimestamps.DiscardedAt.IsZero()
+
+		if runIsActionable && r.TFCNewRun {
+			return errors.New(fmt.Sprintf("Did not create new run. %s in %s in %s is still active", run.ID, r.TFCWorkspaceName, r.TFCOrgName))
+		}
+
+		// If latest run is not actionable, rover will create new run
+		if r.TFCNewRun {
+			// Create new run in specified TFC workspace
+			newRun, err := client.Runs.Create(ctx, tfe.RunCreateOptions{
+				Refresh:   &TRUE,
+				Workspace: ws,
+			})
+			if err != nil {
+				return errors.New(fmt.Sprintf("Unable to generate new run from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+			}
+
+			run = newRun
+
+			slog.Info("Starting new Terraform Cloud run...", "workspace", r.TFCWorkspaceName)
+
+			// Wait maximum of 5 mins
+			for i := 0; i < 30; i++ {
+				run, err := client.Runs.Read(ctx, newRun.ID)
+				if err != nil {
+					return errors.New(fmt.Sprintf("Unable to retrieve run from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+				}
+
+				if run.Plan != nil {
+					planID = run.Plan.ID
+					// Add 20 second timeout so plan JSON becomes available
+					err = sleep(ctx, 20*time.Second)
+					if err != nil {
+						return err
+					}
+					slog.Info("Run completed", "run", newRun.ID)
+					break
+				}
+
+				err = sleep(ctx, 10*time.Second)
+				if err != nil {
+					return err
+				}
+				slog.Info("Waiting for run to complete...", "run", newRun.ID, "waited", fmt.Sprintf("%ds", 10*(i+1)))
+			}
+
+			if planID == "" {
+				return errors.New(fmt.Sprintf("Timeout waiting for plan to complete in %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+			}
+		}
+
+		// Get most recent plan file
+		planBytes, err := client.Plans.JSONOutput(ctx, planID)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to retrieve plan from %s in %s organization. %s", r.TFCWorkspaceName, r.TFCOrgName, err))
+		}
+		// If empty plan file
+		if string(planBytes) == "" {
+			return errors.New(fmt.Sprintf("Empty plan. Check run %s in %s in %s is not pending", run.ID, r.TFCWorkspaceName, r.TFCOrgName))
+		}
+
+		if err := json.Unmarshal(planBytes, &r.Plan); err != nil {
+			return errors.New(fmt.Sprintf("Unable to parse plan (ID: %s) from %s in %s organization.: %s", planID, r.TFCWorkspaceName, r.TFCOrgName, err))
+		}
+
+		return nil
+	}
+
+	tmpDir, err := os.MkdirTemp(r.TmpDir, "rover")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	r.TfPath, err = findTerraform(r.TfPath)
+	if err != nil {
+		return err
+	}
+
+	tf, err := r.newTerraform(r.TfPath)
+	if err != nil {
+		return err
+	}
+
+	// Bound init, plan and show so a hung provider or backend doesn't block Rover forever
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	tfVersion, _, err := tf.Version(ctx, false)
+	if err != nil {
+		return r.commandError(ctx, "version", errors.New(fmt.Sprintf("Unable to get %s version: %s", tfProduct(r.TfPath), err)))
+	}
+	r.TfVersion = tfVersion.String()
+
+	// If user provided path to plan file
+	// Skips terraform init and plan
+	if r.PlanPath != "" {
+		slog.Debug("Using provided plan...")
+
+		fi, err := os.Stat(r.PlanPath)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to find Plan (%s): %s", r.PlanPath, err))
+		}
+		if fi.IsDir() {
+			return errors.New(fmt.Sprintf("Unable to read Plan (%s): path is a directory", r.PlanPath))
+		}
+
+		r.Plan, err = tf.ShowPlanFile(ctx, r.PlanPath)
+		if err != nil {
+			return r.commandError(ctx, "show", errors.New(fmt.Sprintf("Unable to read Plan (%s), is it a Terraform plan file? %s", r.PlanPath, err)))
+		}
+		return nil
+	}
+
+	if r.SkipInit {
+		slog.Debug("Skipping Terraform init, using existing working directory initialization...")
+	} else {
+		slog.Debug("Initializing Terraform...")
+
+		// Create TF Init options
+		var tfInitOptions []tfexec.InitOption
+		// Respect the dependency lock file unless upgrades are requested
+		tfInitOptions = append(tfInitOptions, tfexec.Upgrade(r.Upgrade))
+
+		// Add *.tfbackend files and key=value backend settings
+		for _, tfBackendConfig := range r.TfBackendConfigs {
+			if tfBackendConfig != "" {
+				tfInitOptions = append(tfInitOptions, tfexec.BackendConfig(tfBackendConfig))
+			}
+		}
+
+		// terraform init only accepts -lock-timeout before Terraform 0.15
+		if tfVersion.LessThan(version.Must(version.NewVersion("0.15.0"))) {
+			tfInitOptions = append(tfInitOptions, tfexec.LockTimeout(lockTimeout(r.LockTimeout)))
+		}
+
+		stop := r.captureOutput(tf)
+		err = tf.Init(ctx, tfInitOptions...)
+		stop()
+		if err != nil {
+			return r.commandError(ctx, "init", errors.New(fmt.Sprintf("Unable to initialize Terraform Plan: %s", err)))
+		}
+	}
+
+	// Provider versions are only known once the working directory is initialized
+	_, providerVersions, err := tf.Version(ctx, true)
+	if err != nil {
+		slog.Warn("Unable to get provider versions", "error", err)
+	}
+	r.ProviderVersions = make(map[string]string, len(providerVersions))
+	for provider, v := range providerVersions {
+		r.ProviderVersions[provider] = v.String()
+	}
+
+	if r.WorkspaceName != "" {
+		slog.Info("Selecting workspace...", "workspace", r.WorkspaceName)
+		err = r.selectWorkspace(ctx, tf)
+		if err != nil {
+			return r.commandError(ctx, "workspace selection", err)
+		}
+	}
+
+	if r.FromState {
+		slog.Debug("Reading state...")
+		state, err := tf.Show(ctx)
+		if err != nil {
+			return r.commandError(ctx, "show", errors.New(fmt.Sprintf("Unable to read state: %s", err)))
+		}
+		r.Plan = planFromState(state)
+		return nil
+	}
+
+	slog.Debug("Generating plan...")
+	planPath := fmt.Sprintf("%s/%s-%v", tmpDir, "roverplan", time.Now().Unix())
+
+	// Create TF Plan options
+	var tfPlanOptions []tfexec.PlanOption
+	tfPlanOptions = append(tfPlanOptions, tfexec.Out(planPath))
+	tfPlanOptions = append(tfPlanOptions, tfexec.LockTimeout(lockTimeout(r.LockTimeout)))
+	tfPlanOptions = append(tfPlanOptions, tfexec.Refresh(r.Refresh))
+
+	if r.Parallelism > 0 {
+		slog.Debug("Limiting plan parallelism...", "parallelism", r.Parallelism)
+		tfPlanOptions = append(tfPlanOptions, tfexec.Parallelism(r.Parallelism))
+	}
+
+	if r.Destroy {
+		slog.Debug("Generating destroy plan...")
+		tfPlanOptions = append(tfPlanOptions, tfexec.Destroy(true))
+	}
+
+	// Add Terraform variables and *.tfvars files
+	// Order is preserved so later ones override earlier ones, like Terraform
+	tfPlanOptions = append(tfPlanOptions, r.tfVarOptions()...)
+
+	// TF_VAR_name variables that no *.tfvars file or -var assigns
+	for _, tfVar := range r.envVars() {
+		tfPlanOptions = append(tfPlanOptions, tfexec.Var(tfVar))
+	}
+
+	// Add resource targets
+	for _, target := range r.Targets {
+		if target != "" {
+			tfPlanOptions = append(tfPlanOptions, tfexec.Target(target))
+		}
+	}
+
+	stop := r.captureOutput(tf)
+	defer stop()
+
+	_, err = tf.Plan(ctx, tfPlanOptions...)
+	for retry := 1; err != nil && retry <= r.Retries && ctx.Err() == nil && isTransientError(err); retry++ {
+		backoff := retryDelay(retry)
+		slog.Warn("Plan failed, retrying...", "retry", retry, "retries", r.Retries, "backoff", backoff.String(), "error", err)
+
+		if sleep(ctx, backoff) != nil {
+			break
+		}
+		_, err = tf.Plan(ctx, tfPlanOptions...)
+	}
+	stop()
+	if err != nil {
+		return r.commandError(ctx, "plan", errors.New(fmt.Sprintf("Unable to run Plan: %s", err)))
+	}
+
+	r.Plan, err = tf.ShowPlanFile(ctx, planPath)
+	if err != nil {
+		return r.commandError(ctx, "show", errors.New(fmt.Sprintf("Unable to read Plan: %s", err)))
+	}
+
+	return nil
+}
+
+// captureOutput logs the output of tf's commands at debug level and keeps it
+// to explain failures, until the returned function is called. Commands that
+// print JSON, like show, run without it so plans aren't logged.
+func (r *Rover) captureOutput(tf *tfexec.Terraform) func() {
+	r.output = &terraformOutput{}
+	tf.SetStdout(r.output.writer("stdout"))
+	tf.SetStderr(r.output.writer("stderr"))
+
+	return func() {
+		tf.SetStdout(nil)
+		tf.SetStderr(nil)
+	}
+}
+
+// Plan errors that are likely to succeed on retry
+var transientErrors = regexp.MustCompile(`(?i)Error acquiring the state lock|timeout|timed out|connection reset|connection refused|temporary failure|TLS handshake|too many requests|\b(429|502|503|504)\b|unexpected EOF`)
+
+// isTransientError reports whether a plan error looks like a network or state
+// lock failure rather than a configuration error
+func isTransientError(err error) bool {
+	var locked *tfexec.ErrStateLocked
+	if errors.As(err, &locked) {
+		return true
+	}
+
+	var invalid *tfexec.ErrConfigInvalid
+	var missingVar *tfexec.ErrMissingVar
+	var noInit *tfexec.ErrNoInit
+	var noConfig *tfexec.ErrNoConfig
+	if errors.As(err, &invalid) || errors.As(err, &missingVar) || errors.As(err, &noInit) || errors.As(err, &noConfig) {
+		return false
+	}
+
+	return transientErrors.MatchString(err.Error())
+}
+
+// sleep waits for d or until ctx is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lockTimeout formats d as the NNs or NNm duration Terraform expects for -lock-timeout
+func lockTimeout(d time.Duration) string {
+	if d > 0 && d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	// Round up so sub-second timeouts still wait for the lock
+	return fmt.Sprintf("%ds", (d+time.Second-1)/time.Second)
+}
+
+// commandError explains errors from Terraform commands that exceeded the
+// timeout, needed interactive input or ran remotely, and adds the end of Terraform's output.
+// Rover runs Terraform with -input=false, TF_IN_AUTOMATION and no stdin, so
+// commands fail instead of prompting.
+func (r *Rover) commandError(ctx context.Context, phase string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = errors.New(fmt.Sprintf("%s %s exceeded the %s timeout, use -timeout to increase it", tfProduct(r.TfPath), phase, r.Timeout))
+	} else if inputRequired.MatchString(err.Error()) {
+		err = errors.New(fmt.Sprintf("%s %s needs interactive input, which Rover disables. Set the values it asks for with flags, like -tfVar or -backendConfig: %s", tfProduct(r.TfPath), phase, err))
+	} else if remotePlan.MatchString(err.Error()) {
+		err = errors.New(fmt.Sprintf("%s %s runs remotely with this backend and can't save the plan. Visualize the Terraform Cloud run with -tfcRunID, or the workspace's latest run with -tfcOrg and -tfcWorkspace: %s", tfProduct(r.TfPath), phase, err))
+	}
+
+	// Errors usually include stderr, but not stdout or what Terraform printed
+	// before timing out
+	if r.output != nil {
+		var missing []string
+		for _, line := range r.output.Lines() {
+			if !strings.Contains(err.Error(), line) {
+				missing = append(missing, line)
+			}
+		}
+		if len(missing) > 0 {
+			err = errors.New(fmt.Sprintf("%s\nLast %s output:\n%s", err, tfProduct(r.TfPath), strings.Join(missing, "\n")))
+		}
+	}
+
+	return err
+}
+
+// checkWorkingDir returns an error if dir doesn't exist or, if requireConfig
+// is set, doesn't contain Terraform configuration files
+func checkWorkingDir(dir string, requireConfig bool) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New(fmt.Sprintf("Working directory %s doesn't exist", dir))
+		}
+		return errors.New(fmt.Sprintf("Unable to read working directory %s: %s", dir, err))
+	}
+	if !fi.IsDir() {
+		return errors.New(fmt.Sprintf("Working directory %s is not a directory", dir))
+	}
+
+	if !requireConfig {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to read working directory %s: %s", dir, err))
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() && isConfigFile(e.Name()) {
+			return nil
+		}
+	}
+
+	return errors.New(fmt.Sprintf("Working directory %s has no Terraform configuration files (*.tf or *.tf.json), use -workingDir to set the configuration directory", dir))
+}
+
+// isConfigFile reports whether name is a Terraform or OpenTofu configuration file
+func isConfigFile(name string) bool {
+	for _, ext := range []string{".tf", ".tf.json", ".tofu", ".tofu.json"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// findTerraform resolves the Terraform binary. If tfPath isn't set, it
+// looks up OpenTofu, then Terraform on PATH.
+func findTerraform(tfPath string) (string, error) {
+	if tfPath == "" {
+		for _, bin := range []string{"tofu", "terraform"} {
+			if p, err := exec.LookPath(bin); err == nil {
+				tfPath = p
+				break
+			}
+		}
+		if tfPath == "" {
+			return "", errors.New("Unable to find tofu or terraform on PATH, use -tfPath to set the Terraform binary location")
+		}
+	}
+
+	tfPath, err := filepath.Abs(tfPath)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Unable to resolve Terraform binary path (%s): %s", tfPath, err))
+	}
+
+	slog.Debug(fmt.Sprintf("Using %s binary", tfProduct(tfPath)), "path", tfPath)
+
+	return tfPath, nil
+}
+
+// retryDelay returns the backoff before the retry'th plan retry, counting
+// from 1. It's capped instead of shifted so large -retries don't overflow.
+func retryDelay(retry int) time.Duration {
+	backoff := retryBackoff
+	for i := 1; i < retry && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+// Products tfProduct found, by binary path
+var tfProducts sync.Map
+
+// tfProduct returns whether the binary is Terraform or OpenTofu from its
+// version output, like "OpenTofu v1.6.0". The binary's name is only checked
+// if the output doesn't say.
+func tfProduct(tfPath string) string {
+	if product, ok := tfProducts.Load(tfPath); ok {
+		return product.(string)
+	}
+
+	product := ""
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, tfPath, "version").Output()
+	if err == nil {
+		out = bytes.TrimSpace(out)
+		switch {
+		case bytes.HasPrefix(out, []byte("OpenTofu ")):
+			product = "OpenTofu"
+		case bytes.HasPrefix(out, []byte("Terraform ")):
+			product = "Terraform"
+		}
+	}
+	if product == "" {
+		product = "Terraform"
+		if strings.HasPrefix(filepath.Base(tfPath), "tofu") {
+			product = "OpenTofu"
+		}
+	}
+
+	tfProducts.Store(tfPath, product)
+	return product
+}
+
+// selectWorkspace selects r.WorkspaceName, creating it if it doesn't exist
+func (r *Rover) selectWorkspace(ctx context.Context, tf *tfexec.Terraform) error {
+	workspaces, _, err := tf.WorkspaceList(ctx)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to list workspaces: %s", err))
+	}
+
+	for _, ws := range workspaces {
+		if ws == r.WorkspaceName {
+			err = tf.WorkspaceSelect(ctx, r.WorkspaceName)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Unable to select workspace (%s): %s", r.WorkspaceName, err))
+			}
+			return nil
+		}
+	}
+
+	slog.Info("Workspace doesn't exist, creating it...", "workspace", r.WorkspaceName)
+	err = tf.WorkspaceNew(ctx, r.WorkspaceName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to create workspace (%s): %s", r.WorkspaceName, err))
+	}
+
+	return nil
+}
+
+// checkPlanFormatVersion warns if the plan's format version is outside the
+// versions Rover has been tested against, whose plans may be read partially.
+// With -strictVersion, it returns an error instead.
+func (r *Rover) checkPlanFormatVersion(kind string, plan *tfjson.Plan) error {
+	v, err := version.NewVersion(plan.FormatVersion)
+	if err != nil {
+		if r.StrictVersion {
+			return errors.New(fmt.Sprintf("Unable to parse %s format version %q: %s", kind, plan.FormatVersion, err))
+		}
+		slog.Warn(fmt.Sprintf("Unable to parse %s format version", kind), "version", plan.FormatVersion, "error", err)
+		return nil
+	}
+
+	c, err := version.NewConstraint(testedPlanFormatVersions)
+	if err != nil {
+		return err
+	}
+	if c.Check(v) {
+		return nil
+	}
+
+	if r.StrictVersion {
+		return errors.New(fmt.Sprintf("%s format version %s is not supported by Rover (supported: %s), generate it with a compatible Terraform version", kind, v, testedPlanFormatVersions))
+	}
+	slog.Warn(fmt.Sprintf("%s format version is not supported by Rover, the visualization may be incomplete. Use -strictVersion to fail instead", kind), "version", v.String(), "supported", testedPlanFormatVersions)
+	return nil
+}
+
+func showJSON(g interface{}) error {
+	j, err := json.Marshal(g)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error producing JSON: %s", err))
+	}
+	slog.Debug(string(j))
+	return nil
+}
+
+func showModuleJSON(module *tfconfig.Module) error {
+	j, err := json.MarshalIndent(module, "", "  ")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error producing JSON: %s", err))
+	}
+	_, err = os.Stdout.Write(append(j, '\n'))
+	return err
+}
+
+// saveJSONToFile writes j to <path>/<fileType>.json, creating path if needed
+func saveJSONToFile(fileType string, path string, j interface{}) (string, error) {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error producing %s JSON: %s", fileType, err))
+	}
+
+	err = os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	fname := filepath.Join(path, fmt.Sprintf("%s.json", fileType))
+
+	f, err := os.Create(fname)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	_, err = f.Write(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fname, nil
+}
+
+// namedAsset is a generated asset and its file type
+type namedAsset struct {
+	fileType string
+	j        interface{}
+}
+
+// namedAssets returns the generated assets in the order they're written
+func (r *Rover) namedAssets() []namedAsset {
+	assets := []namedAsset{
+		{"plan", r.Plan},
+		{"rso", r.RSO},
+		{"map", r.Map},
+		{"graph", r.Graph},
+		{"meta", r.Meta},
+		{"providers", r.Providers},
+	}
+	if r.Diff != nil {
+		assets = append(assets, namedAsset{"diff", r.Diff})
+	}
+	if r.Legend != nil {
+		assets = append(assets, namedAsset{"legend", r.Legend})
+	}
+	return assets
+}
+
+// WriteAssetsJSON writes the generated assets to w as a single JSON object
+// keyed by file type, like {"plan":...,"rso":...}
+func (r *Rover) WriteAssetsJSON(w io.Writer) error {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, a := range r.namedAssets() {
+		j, err := json.Marshal(a.j)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error producing %s JSON: %s", a.fileType, err))
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%q:", a.fileType)
+		b.Write(j)
+	}
+	b.WriteString("}\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// WriteAssets saves the generated assets as JSON files in dir
+func (r *Rover) WriteAssets(dir string) error {
+	for _, a := range r.namedAssets() {
+		fname, err := saveJSONToFile(a.fileType, dir, a.j)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Unable to write %s: %s", a.fileType, err))
+		}
+		slog.Info("Wrote asset", "file", fname)
+	}
+
+	return nil
+}
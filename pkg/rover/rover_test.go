@@ -0,0 +1,142 @@
+package rover
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+func TestCheckPlanFormatVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		// Whether -strictVersion fails, plans are only warned about otherwise
+		strictErr bool
+	}{
+		{"fabricated", "42.0-fabricated", true},
+		{"unparseable", "not a version", true},
+		{"empty", "", true},
+		{"oldest", "0.1", false},
+		{"terraform 1.0", "1.0", false},
+		{"terraform 1.2", "1.1", false},
+		{"terraform 1.5", "1.2", false},
+		{"too old", "0.0.9", true},
+		{"too new", "1.3", true},
+		{"next major", "2.0", true},
+	}
+
+	for _, tt := range tests {
+		for _, strict := range []bool{false, true} {
+			r := New(Config{StrictVersion: strict})
+			err := r.checkPlanFormatVersion("Plan", &tfjson.Plan{FormatVersion: tt.version})
+
+			wantErr := strict && tt.strictErr
+			if (err != nil) != wantErr {
+				t.Errorf("%s (%q), strict %t: error = %v, want error %t", tt.name, tt.version, strict, err, wantErr)
+			}
+		}
+	}
+}
+
+func TestPlanVarOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf":     "variable \"size\" {}\nvariable \"region\" {}\n",
+		"base.tfvars": "size = 1\nregion = \"us-east-1\"\n",
+		"prod.tfvars": "size = 4\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Record the arguments plan runs with
+	out := filepath.Join(t.TempDir(), "args")
+	r := New(Config{
+		Name:       "rover",
+		WorkingDir: dir,
+		TfPath:     fakeTerraform(t, map[string]string{"plan": `echo "$@" > "` + out + `"; exit 1`}),
+		TfVars: []TfVar{
+			{Value: "base.tfvars", File: true},
+			{Value: "region=eu-west-1"},
+			{Value: "size=2"},
+			{Value: "prod.tfvars", File: true},
+		},
+	})
+	if err := r.Generate(context.Background()); err == nil {
+		t.Fatal("Generate succeeded, want plan error")
+	}
+
+	args, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tfexec passes var files first, so size=2, which prod.tfvars
+	// overrides, is left out
+	for _, want := range []string{"-var-file=base.tfvars -var-file=prod.tfvars", "-var region=eu-west-1"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("plan arguments = %s, want them to contain %s", args, want)
+		}
+	}
+	if strings.Contains(string(args), "size=2") {
+		t.Errorf("plan arguments = %s, want size=2 overridden by prod.tfvars", args)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{5, 32 * time.Second},
+		{6, time.Minute},
+		{64, time.Minute},
+		{100, time.Minute},
+		{math.MaxInt, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.retry); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.retry, got, tt.want)
+		}
+	}
+}
+
+func TestTfProduct(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake binaries are shell scripts")
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{name: "terraform", version: "echo 'OpenTofu v1.6.0'; echo 'on linux_amd64'", want: "OpenTofu"},
+		{name: "tofu", version: "echo 'Terraform v1.6.0'; echo 'on linux_amd64'", want: "Terraform"},
+		// The name is only checked if the version doesn't say
+		{name: "tofu", version: "exit 1", want: "OpenTofu"},
+		{name: "tofu-1.6", version: "echo '1.6.0'", want: "OpenTofu"},
+		{name: "terraform", version: "exit 1", want: "Terraform"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), tt.name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+tt.version+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if got := tfProduct(path); got != tt.want {
+			t.Errorf("tfProduct(%s) with %q = %s, want %s", tt.name, tt.version, got, tt.want)
+		}
+	}
+}
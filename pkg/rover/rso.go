@@ -1,12 +1,13 @@
-package main
+package rover
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-config-inspect/tfconfig"
 	tfjson "github.com/hashicorp/terraform-json"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,6 +19,71 @@ type ResourcesOverview struct {
 	Locations map[string]string          `json:"locations,omitempty"`
 	States    map[string]*StateOverview  `json:"states,omitempty"`
 	Configs   map[string]*ConfigOverview `json:"configs,omitempty"`
+	// Resource addresses grouped by provider (aws, google, etc.)
+	Providers map[string][]string        `json:"providers,omitempty"`
+	Outputs   map[string]*OutputOverview `json:"outputs,omitempty"`
+	Summary   *ChangeSummary             `json:"summary,omitempty"`
+}
+
+// ChangeSummary counts the resource changes in the plan
+type ChangeSummary struct {
+	Create  int `json:"create"`
+	Update  int `json:"update"`
+	Delete  int `json:"delete"`
+	Replace int `json:"replace"`
+	Read    int `json:"read"`
+	NoOp    int `json:"no_op"`
+	// Counted like terraform plan's "Plan: X to add, Y to change, Z to destroy."
+	ToAdd     int `json:"to_add"`
+	ToChange  int `json:"to_change"`
+	ToDestroy int `json:"to_destroy"`
+	// Resources changed outside of Terraform, detected by refresh
+	Drift int `json:"drift"`
+	// Resources moved to a new address, with moved blocks or terraform state mv
+	Move int `json:"move"`
+	// Data sources, which are read rather than changed
+	DataSources int `json:"data_sources"`
+}
+
+// add counts a resource change
+func (s *ChangeSummary) add(actions tfjson.Actions) {
+	switch {
+	case actions.Replace():
+		// ["delete", "create"] or ["create", "delete"]
+		s.Replace++
+		s.ToAdd++
+		s.ToDestroy++
+	case actions.Create():
+		s.Create++
+		s.ToAdd++
+	case actions.Update():
+		s.Update++
+		s.ToChange++
+	case actions.Delete():
+		s.Delete++
+		s.ToDestroy++
+	case actions.Read():
+		s.Read++
+	case actions.NoOp():
+		s.NoOp++
+	}
+}
+
+// HasChanges reports whether the plan creates, updates, deletes, replaces or
+// moves any resource
+func (s *ChangeSummary) HasChanges() bool {
+	return s.Create+s.Update+s.Delete+s.Replace+s.Move > 0
+}
+
+// Replaces sensitive values unless -showSensitive is set
+const sensitiveValue = "(sensitive)"
+
+// OutputOverview is a root module output value change
+type OutputOverview struct {
+	ChangeAction Action      `json:"change_action"`
+	Before       interface{} `json:"before,omitempty"`
+	After        interface{} `json:"after,omitempty"`
+	Sensitive    bool        `json:"sensitive,omitempty"`
 }
 
 // ResourceOverview is a modified tfjson.Plan
@@ -29,6 +95,12 @@ type StateOverview struct {
 	Children  map[string]*StateOverview `json:"children,omitempty"`
 	Type      ResourceType              `json:"type,omitempty"`
 	IsParent  bool                      `json:"isparent,omitempty"`
+	// Status relative to the -comparePlan baseline
+	Diff string `json:"diff,omitempty"`
+	// Set if the resource changed outside of Terraform
+	Drift bool `json:"drift,omitempty"`
+	// Address the resource is moved from
+	PreviousAddress string `json:"previous_address,omitempty"`
 }
 
 type ConfigOverview struct {
@@ -45,21 +117,64 @@ type ModuleLocations struct {
 }
 
 type ModuleLocation struct {
-	Key    string `json:"Key,omitempty""`
+	Key    string `json:"Key,omitempty"`
 	Source string `json:"Source,omitempty"`
 	Dir    string `json:"Dir,omitempty"`
 }
 
+// loadModule loads the module in dir from the filesystem. Its diagnostics are
+// logged and it returns an error if the module can't be loaded.
+func loadModule(dir string) (*tfconfig.Module, error) {
+	module, diags := tfconfig.LoadModule(dir)
+	logDiagnostics(diags)
+
+	if module == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to load module %s", dir))
+	}
+
+	errs := 0
+	for _, d := range diags {
+		if d.Severity == tfconfig.DiagError {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return nil, errors.New(fmt.Sprintf("%d configuration error(s) in %s", errs, dir))
+	}
+
+	return module, nil
+}
+
+// logDiagnostics logs each diagnostic with its severity and source position,
+// like Terraform reports configuration problems
+func logDiagnostics(diags tfconfig.Diagnostics) {
+	for _, d := range diags {
+		attrs := []interface{}{"summary", d.Summary}
+		if d.Detail != "" {
+			attrs = append(attrs, "detail", d.Detail)
+		}
+		if d.Pos != nil {
+			attrs = append(attrs, "file", d.Pos.Filename, "line", d.Pos.Line)
+		}
+
+		if d.Severity == tfconfig.DiagError {
+			slog.Error("Configuration error", attrs...)
+		} else {
+			slog.Warn("Configuration warning", attrs...)
+		}
+	}
+}
+
 // PopulateModuleLocations Parses the modules.json file in the .terraform folder, if it exists
 // The module locations are then added to rso.Locations and referenced when loading
 // modules from the filesystem with tfconfig.LoadModule
-func (r *rover) PopulateModuleLocations(moduleJSONFile string, locations map[string]string) {
+func (r *Rover) PopulateModuleLocations(moduleJSONFile string, locations map[string]string) {
 
 	moduleLocations := ModuleLocations{}
 
 	jsonFile, err := os.Open(moduleJSONFile)
 	if err != nil {
-		log.Println("No submodule configurations found...")
+		slog.Debug("No submodule configurations found...")
 	}
 	defer jsonFile.Close()
 
@@ -76,7 +191,7 @@ func (r *rover) PopulateModuleLocations(moduleJSONFile string, locations map[str
 	}
 }
 
-func (r *rover) PopulateConfigs(parent string, parentKey string, rso *ResourcesOverview, config *tfjson.ConfigModule) {
+func (r *Rover) PopulateConfigs(parent string, parentKey string, rso *ResourcesOverview, config *tfjson.ConfigModule) {
 
 	ml := rso.Locations
 	rc := rso.Configs
@@ -139,12 +254,20 @@ func (r *rover) PopulateConfigs(parent string, parentKey string, rso *ResourcesO
 		}
 
 		childPath := ml[childKey]
-		child, _ := tfconfig.LoadModule(childPath)
+		var child *tfconfig.Module
+		// Modules that haven't been installed aren't in modules.json
+		err := errors.New("module isn't installed")
+		if childPath != "" {
+			child, err = loadModule(childPath)
+		}
 		// If module can be loaded from filesystem
-		if !child.Diagnostics.HasErrors() {
+		if err == nil {
 			rc[mn].Module = child
+			if !r.ShowSensitive {
+				redactModuleDefaults(child, m.Module)
+			}
 		} else {
-			log.Printf("Continuing without loading module from filesystem: %s\n", childKey)
+			slog.Warn("Continuing without loading module from filesystem", "module", childKey, "error", err)
 		}
 
 		rc[mn].ModuleConfig = m
@@ -153,7 +276,7 @@ func (r *rover) PopulateConfigs(parent string, parentKey string, rso *ResourcesO
 	}
 }
 
-func (r *rover) PopulateModuleState(rso *ResourcesOverview, module *tfjson.StateModule, prior bool) {
+func (r *Rover) PopulateModuleState(rso *ResourcesOverview, module *tfjson.StateModule, prior bool) {
 	childIndex := regexp.MustCompile(`\[[^[\]]*\]$`)
 
 	rs := rso.States
@@ -262,15 +385,19 @@ func (r *rover) PopulateModuleState(rso *ResourcesOverview, module *tfjson.State
 
 // GenerateResourceOverview - Overview of files and their resources
 // Groups different resource types together
-func (r *rover) GenerateResourceOverview() error {
-	log.Println("Generating resource overview...")
+func (r *Rover) GenerateResourceOverview() error {
+	slog.Debug("Generating resource overview...")
 
 	matchBrackets := regexp.MustCompile(`\[[^\[\]]*\]`)
+	childIndex := regexp.MustCompile(`\[[^[\]]*\]$`)
 	rso := &ResourcesOverview{}
 
 	rso.Locations = make(map[string]string)
 	rso.Configs = make(map[string]*ConfigOverview)
 	rso.States = make(map[string]*StateOverview)
+	rso.Providers = make(map[string][]string)
+	rso.Outputs = make(map[string]*OutputOverview)
+	rso.Summary = &ChangeSummary{}
 
 	rc := rso.Configs
 	rs := rso.States
@@ -281,13 +408,15 @@ func (r *rover) GenerateResourceOverview() error {
 
 	// Create root module configuration
 	rc[""] = &ConfigOverview{}
-	rootModule, _ := tfconfig.LoadModule(r.WorkingDir)
+	rootModule, err := loadModule(r.WorkingDir)
 	// If module can be loaded from filesystem
-	if !rootModule.Diagnostics.HasErrors() {
+	if err == nil {
 		rc[""].Module = rootModule
+		if !r.ShowSensitive {
+			redactModuleDefaults(rootModule, r.Plan.Config.RootModule)
+		}
 	} else {
-		log.Printf("Could not load configuration from: %v\n", r.WorkingDir)
-		log.Printf("Continuing without configuration file data...")
+		slog.Warn("Could not load configuration, continuing without configuration file data", "workingDir", r.WorkingDir, "error", err)
 	}
 
 	rc[""].ModuleConfig = &tfjson.ModuleCall{}
@@ -329,22 +458,42 @@ func (r *rover) GenerateResourceOverview() error {
 			rs[outputName] = &StateOverview{}
 		}
 
-		// If before/after sensitive, set value to "Sensitive Value"
+		beforeSensitive, _ := output.BeforeSensitive.(bool)
+		afterSensitive, _ := output.AfterSensitive.(bool)
+
+		// If before/after sensitive, set value to "(sensitive)"
 		if !r.ShowSensitive {
-			if output.BeforeSensitive != nil {
-				if output.BeforeSensitive.(bool) {
-					output.Before = "Sensitive Value"
-				}
+			if beforeSensitive {
+				output.Before = sensitiveValue
 			}
-			if output.AfterSensitive != nil {
-				if output.AfterSensitive.(bool) {
-					output.After = "Sensitive Value"
-				}
+			if afterSensitive {
+				output.After = sensitiveValue
 			}
 		}
 
 		rs[outputName].Change = *output
 		rs[outputName].Type = ResourceTypeOutput
+
+		o := &OutputOverview{
+			Before:    output.Before,
+			After:     output.After,
+			Sensitive: beforeSensitive || afterSensitive,
+		}
+		if len(output.Actions) > 0 {
+			o.ChangeAction = Action(string(output.Actions[0]))
+			if len(output.Actions) > 1 {
+				o.ChangeAction = ActionReplace
+			}
+		}
+		rso.Outputs[outputName] = o
+	}
+
+	// Moved resources are shown at their new address only
+	movedFrom := make(map[string]bool)
+	for _, resource := range r.Plan.ResourceChanges {
+		if resource.PreviousAddress != "" && resource.PreviousAddress != resource.Address {
+			movedFrom[resource.PreviousAddress] = true
+		}
 	}
 
 	// Loop through resource changes
@@ -353,6 +502,10 @@ func (r *rover) GenerateResourceOverview() error {
 		configId := matchBrackets.ReplaceAllString(id, "")
 		parent := resource.ModuleAddress
 
+		if movedFrom[id] && resource.Change != nil && resource.Change.Actions.Delete() {
+			continue
+		}
+
 		if resource.Change != nil {
 
 			// If has parent, create parent if doesn't exist
@@ -361,19 +514,46 @@ func (r *rover) GenerateResourceOverview() error {
 				rs[parent].Children = make(map[string]*StateOverview)
 			}
 
+			resourceType := ResourceTypeResource
+			if resource.Mode == "data" {
+				resourceType = ResourceTypeData
+			}
+
 			// Add resource to parent
 			// Create resource if doesn't exist
 			if _, ok := rs[id]; !ok {
 				rs[id] = &StateOverview{}
-				if resource.Mode == "data" {
-					rs[id].Type = ResourceTypeData
+				rs[id].Type = resourceType
+
+				// Instances of resources w/ count or for_each belong to the resource
+				if childIndex.MatchString(id) {
+					resourceParent := childIndex.ReplaceAllString(id, "")
+					if _, ok := rs[resourceParent]; !ok {
+						rs[resourceParent] = &StateOverview{}
+						rs[resourceParent].Children = make(map[string]*StateOverview)
+						rs[resourceParent].Type = resourceType
+					}
+					rs[parent].Children[resourceParent] = rs[resourceParent]
+					rs[resourceParent].Children[id] = rs[id]
 				} else {
-					rs[id].Type = ResourceTypeResource
+					rs[parent].Children[id] = rs[id]
 				}
-				rs[parent].Children[id] = rs[id]
 			}
 			rs[id].Change = *resource.Change
 
+			rso.Summary.add(resource.Change.Actions)
+			if resourceType == ResourceTypeData {
+				rso.Summary.DataSources++
+			}
+
+			if resource.PreviousAddress != "" && resource.PreviousAddress != id {
+				rs[id].PreviousAddress = resource.PreviousAddress
+				rso.Summary.Move++
+			}
+
+			provider := providerGroup(resource.ProviderName)
+			rso.Providers[provider] = append(rso.Providers[provider], id)
+
 			// Create resource config if doesn't exist
 			if _, ok := rc[configId]; !ok {
 				rc[configId] = &ConfigOverview{}
@@ -392,3 +572,16 @@ func (r *rover) GenerateResourceOverview() error {
 
 	return nil
 }
+
+// providerGroup returns the provider type from a provider address like
+// registry.terraform.io/hashicorp/aws. Built-in and unknown providers are
+// grouped as "other".
+func providerGroup(providerName string) string {
+	if providerName == "" || strings.HasPrefix(providerName, "terraform.io/builtin/") {
+		return "other"
+	}
+
+	parts := strings.Split(providerName, "/")
+	// Terraform 0.12 uses provider.aws
+	return strings.TrimPrefix(parts[len(parts)-1], "provider.")
+}
@@ -0,0 +1,90 @@
+package rover
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SearchResult is a resource matching a search query
+type SearchResult struct {
+	Address      string       `json:"address"`
+	Type         ResourceType `json:"type"`
+	ResourceType string       `json:"resource_type"`
+	Name         string       `json:"name"`
+	Change       string       `json:"change,omitempty"`
+	// ID of the graph node to focus, empty if the resource isn't in the graph
+	GraphID string `json:"graph_id,omitempty"`
+}
+
+// Module path and data prefix of resource addresses
+var addressPrefix = regexp.MustCompile(`^(module\.[^.\[]+(\[[^\]]*\])?\.)*(data\.)?`)
+
+// Search returns the resources whose address, type or name matches query,
+// sorted by address. Queries with *, ? or [ are matched as glob patterns,
+// other queries as substrings. Both are case-insensitive.
+func (r *Rover) Search(query string) []SearchResult {
+	query = strings.ToLower(strings.TrimSpace(query))
+	results := []SearchResult{}
+	if query == "" || r.RSO == nil {
+		return results
+	}
+
+	glob := strings.ContainsAny(query, "*?[")
+	matches := func(s string) bool {
+		s = strings.ToLower(s)
+		if glob {
+			ok, _ := path.Match(query, s)
+			return ok
+		}
+		return strings.Contains(s, query)
+	}
+
+	nodes := make(map[string]bool, len(r.Graph.Nodes))
+	for _, n := range r.Graph.Nodes {
+		nodes[n.Data.ID] = true
+	}
+
+	for address, so := range r.RSO.States {
+		if so.Type != ResourceTypeResource && so.Type != ResourceTypeData {
+			continue
+		}
+
+		resourceType, name, _ := strings.Cut(addressPrefix.ReplaceAllString(address, ""), ".")
+		if i := strings.Index(name, "["); i > 0 {
+			name = name[:i]
+		}
+		if !matches(address) && !matches(resourceType) && !matches(name) {
+			continue
+		}
+
+		res := SearchResult{
+			Address:      address,
+			Type:         so.Type,
+			ResourceType: resourceType,
+			Name:         name,
+		}
+		if len(so.Change.Actions) > 0 {
+			res.Change = string(so.Change.Actions[0])
+			if so.Change.Actions.Replace() {
+				res.Change = string(ActionReplace)
+			}
+		}
+
+		// Collapsed instances are focused on their resource
+		if nodes[address] {
+			res.GraphID = address
+		} else if i := strings.LastIndex(address, "["); i > 0 && nodes[address[:i]] {
+			res.GraphID = address[:i]
+		}
+
+		results = append(results, res)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Address < results[j].Address
+	})
+
+	return results
+}
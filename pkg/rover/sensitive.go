@@ -0,0 +1,145 @@
+package rover
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// redactPlan replaces sensitive values in the plan with "(sensitive)" so they
+// aren't served by the API or written to the generated assets
+func redactPlan(plan *tfjson.Plan) {
+	sensitiveVars := make(map[string]bool)
+
+	if plan.Config != nil && plan.Config.RootModule != nil {
+		for name, v := range plan.Config.RootModule.Variables {
+			if v.Sensitive {
+				sensitiveVars[name] = true
+				if v.Default != nil {
+					v.Default = sensitiveValue
+				}
+			}
+		}
+	}
+
+	for name, v := range plan.Variables {
+		if sensitiveVars[name] {
+			v.Value = sensitiveValue
+		}
+	}
+
+	for _, rc := range plan.ResourceChanges {
+		redactChange(rc.Change)
+	}
+
+	for _, rc := range plan.ResourceDrift {
+		redactChange(rc.Change)
+	}
+
+	for _, oc := range plan.OutputChanges {
+		redactChange(oc)
+	}
+
+	redactStateValues(plan.PlannedValues)
+
+	if plan.PriorState != nil {
+		redactStateValues(plan.PriorState.Values)
+	}
+}
+
+// redactModuleDefaults hides the defaults of variables the configuration marks
+// as sensitive. tfconfig doesn't read whether variables are sensitive.
+func redactModuleDefaults(module *tfconfig.Module, config *tfjson.ConfigModule) {
+	if module == nil || config == nil {
+		return
+	}
+
+	for name, v := range module.Variables {
+		if c, ok := config.Variables[name]; ok && c.Sensitive && v.Default != nil {
+			v.Default = sensitiveValue
+		}
+	}
+}
+
+func redactChange(change *tfjson.Change) {
+	if change == nil {
+		return
+	}
+	change.Before = redactValue(change.Before, change.BeforeSensitive)
+	change.After = redactValue(change.After, change.AfterSensitive)
+}
+
+func redactStateValues(values *tfjson.StateValues) {
+	if values == nil {
+		return
+	}
+
+	for _, o := range values.Outputs {
+		if o.Sensitive {
+			o.Value = sensitiveValue
+		}
+	}
+
+	redactStateModule(values.RootModule)
+}
+
+func redactStateModule(module *tfjson.StateModule) {
+	if module == nil {
+		return
+	}
+
+	for _, rs := range module.Resources {
+		if len(rs.SensitiveValues) == 0 {
+			continue
+		}
+
+		var sensitive map[string]interface{}
+		if err := json.Unmarshal(rs.SensitiveValues, &sensitive); err != nil {
+			continue
+		}
+
+		for k, s := range sensitive {
+			if _, ok := rs.AttributeValues[k]; ok {
+				rs.AttributeValues[k] = redactValue(rs.AttributeValues[k], s)
+			}
+		}
+	}
+
+	for _, child := range module.ChildModules {
+		redactStateModule(child)
+	}
+}
+
+// redactValue replaces the parts of value marked as sensitive. sensitive
+// mirrors the structure of value, with true for each sensitive leaf.
+func redactValue(value interface{}, sensitive interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	switch s := sensitive.(type) {
+	case bool:
+		if s {
+			return sensitiveValue
+		}
+	case map[string]interface{}:
+		if v, ok := value.(map[string]interface{}); ok {
+			for k, ks := range s {
+				if _, ok := v[k]; ok {
+					v[k] = redactValue(v[k], ks)
+				}
+			}
+		}
+	case []interface{}:
+		if v, ok := value.([]interface{}); ok {
+			for i := range s {
+				if i < len(v) {
+					v[i] = redactValue(v[i], s[i])
+				}
+			}
+		}
+	}
+
+	return value
+}
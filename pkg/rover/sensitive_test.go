@@ -0,0 +1,45 @@
+package rover
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+func TestRedactPlanResourceDrift(t *testing.T) {
+	planJSON := `{
+		"format_version": "1.2",
+		"resource_drift": [{
+			"address": "aws_db_instance.main",
+			"mode": "managed",
+			"type": "aws_db_instance",
+			"name": "main",
+			"change": {
+				"actions": ["update"],
+				"before": {"name": "main", "password": "hunter2"},
+				"after": {"name": "main", "password": "hunter3"},
+				"before_sensitive": {"password": true},
+				"after_sensitive": {"password": true}
+			}
+		}]
+	}`
+
+	plan := &tfjson.Plan{}
+	if err := json.Unmarshal([]byte(planJSON), plan); err != nil {
+		t.Fatal(err)
+	}
+
+	redactPlan(plan)
+
+	change := plan.ResourceDrift[0].Change
+	for name, v := range map[string]interface{}{"before": change.Before, "after": change.After} {
+		values := v.(map[string]interface{})
+		if values["password"] != sensitiveValue {
+			t.Errorf("%s password = %v, want %q", name, values["password"], sensitiveValue)
+		}
+		if values["name"] != "main" {
+			t.Errorf("%s name = %v, want unredacted main", name, values["name"])
+		}
+	}
+}
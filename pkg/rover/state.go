@@ -0,0 +1,69 @@
+package rover
+
+import (
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// planFromState adapts state into a plan where every resource and output is
+// unchanged, so existing infrastructure can be visualized without a plan
+func planFromState(state *tfjson.State) *tfjson.Plan {
+	plan := &tfjson.Plan{
+		FormatVersion:    state.FormatVersion,
+		TerraformVersion: state.TerraformVersion,
+		PriorState:       state,
+		PlannedValues:    state.Values,
+		// State doesn't contain configuration
+		Config: &tfjson.Config{
+			RootModule: &tfjson.ConfigModule{},
+		},
+		OutputChanges: make(map[string]*tfjson.Change),
+	}
+
+	if state.Values == nil {
+		return plan
+	}
+
+	for name, output := range state.Values.Outputs {
+		plan.OutputChanges[name] = &tfjson.Change{
+			Actions:         tfjson.Actions{tfjson.ActionNoop},
+			Before:          output.Value,
+			After:           output.Value,
+			BeforeSensitive: output.Sensitive,
+			AfterSensitive:  output.Sensitive,
+		}
+	}
+
+	if state.Values.RootModule != nil {
+		plan.ResourceChanges = resourceChangesFromState(state.Values.RootModule)
+	}
+
+	return plan
+}
+
+// resourceChangesFromState returns a no-op change for every resource in module and its children
+func resourceChangesFromState(module *tfjson.StateModule) []*tfjson.ResourceChange {
+	var changes []*tfjson.ResourceChange
+
+	for _, rst := range module.Resources {
+		changes = append(changes, &tfjson.ResourceChange{
+			Address:       rst.Address,
+			ModuleAddress: module.Address,
+			Mode:          rst.Mode,
+			Type:          rst.Type,
+			Name:          rst.Name,
+			Index:         rst.Index,
+			ProviderName:  rst.ProviderName,
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{tfjson.ActionNoop},
+				Before:  rst.AttributeValues,
+				After:   rst.AttributeValues,
+			},
+		})
+	}
+
+	for _, childModule := range module.ChildModules {
+		changes = append(changes, resourceChangesFromState(childModule)...)
+	}
+
+	return changes
+}
@@ -0,0 +1,236 @@
+package rover
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"sort"
+)
+
+// SVG layout sizes, in pixels
+const (
+	svgNodeHeight    = 30
+	svgCharWidth     = 7
+	svgPadding       = 12
+	svgGap           = 24
+	svgClusterHeader = 22
+)
+
+// svgBox is a node or cluster in the SVG layout
+type svgBox struct {
+	node     Node
+	children []*svgBox
+	rank     int
+	x, y     float64
+	w, h     float64
+}
+
+// GenerateSVG renders the graph as a standalone SVG image. Nodes with children
+// (modules, files and resource types) are drawn as boxed clusters. Within each
+// cluster, nodes are laid out in rows so that resources are drawn above the
+// resources they depend on.
+func (r *Rover) GenerateSVG() []byte {
+	children, roots := graphTree(r.Graph)
+	edges := graphEdges(r.Graph)
+	ranks := graphRanks(edges)
+
+	boxes := make(map[string]*svgBox, len(r.Graph.Nodes))
+	var build func(n Node) *svgBox
+	build = func(n Node) *svgBox {
+		b := &svgBox{node: n, rank: ranks[n.Data.ID]}
+		boxes[n.Data.ID] = b
+		for _, c := range children[n.Data.ID] {
+			cb := build(c)
+			if cb.rank > b.rank {
+				b.rank = cb.rank
+			}
+			b.children = append(b.children, cb)
+		}
+		return b
+	}
+
+	root := &svgBox{}
+	for _, n := range roots {
+		root.children = append(root.children, build(n))
+	}
+
+	root.layout()
+	root.position(0, 0)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n", root.w, root.h, root.w, root.h)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#666"/></marker></defs>` + "\n")
+	fmt.Fprintf(&b, `<rect width="%.0f" height="%.0f" fill="white"/>`+"\n", root.w, root.h)
+
+	for _, c := range root.children {
+		c.write(&b)
+	}
+
+	cyclic := cyclicEdges(r.Graph)
+	for _, e := range edges {
+		s, t := boxes[e.Data.Source], boxes[e.Data.Target]
+		x1, y1 := s.x+s.w/2, s.y+s.h
+		x2, y2 := t.x+t.w/2, t.y
+		// Dependencies in the same row or above are connected side to side
+		if t.y <= s.y {
+			y1, y2 = s.y+s.h/2, t.y+t.h/2
+			if t.x > s.x {
+				x1, x2 = s.x+s.w, t.x
+			} else {
+				x1, x2 = s.x, t.x+t.w
+			}
+		}
+		stroke := "#666"
+		if cyclic[e.Data.ID] {
+			stroke = "red"
+		}
+		dash := ""
+		switch e.Data.Kind {
+		case EdgeKindDependsOn:
+			dash = ` stroke-dasharray="6 3"`
+		case EdgeKindModuleCall:
+			dash = ` stroke-dasharray="2 2"`
+		}
+		fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="%s"%s marker-end="url(#arrow)"/>`+"\n", x1, y1, x2, y2, stroke, dash)
+	}
+
+	b.WriteString("</svg>\n")
+
+	return b.Bytes()
+}
+
+// layout sizes the box and lays out its children in rows by rank
+func (b *svgBox) layout() {
+	if len(b.children) == 0 {
+		b.w = float64(len(b.node.Data.ID)*svgCharWidth + 2*svgPadding)
+		b.h = svgNodeHeight
+		return
+	}
+
+	for _, c := range b.children {
+		c.layout()
+	}
+
+	// Higher ranks depend on lower ranks, so they're drawn first
+	sort.SliceStable(b.children, func(i, j int) bool {
+		return b.children[i].rank > b.children[j].rank
+	})
+
+	width, height := 0.0, 0.0
+	rowWidth, rowHeight := 0.0, 0.0
+	for i, c := range b.children {
+		if i > 0 && c.rank != b.children[i-1].rank {
+			width = maxFloat(width, rowWidth)
+			height += rowHeight + svgGap
+			rowWidth, rowHeight = 0, 0
+		}
+		if rowWidth > 0 {
+			rowWidth += svgGap
+		}
+		// Position relative to the box, made absolute by position
+		c.x, c.y = rowWidth, height
+		rowWidth += c.w
+		rowHeight = maxFloat(rowHeight, c.h)
+	}
+	width = maxFloat(width, rowWidth)
+	height += rowHeight
+
+	b.w = width + 2*svgPadding
+	b.h = height + 2*svgPadding + b.header()
+}
+
+// header returns the height of the cluster's label. The root box isn't labeled.
+func (b *svgBox) header() float64 {
+	if b.node.Data.ID == "" {
+		return 0
+	}
+	return svgClusterHeader
+}
+
+// position moves the box and its children to absolute coordinates
+func (b *svgBox) position(x float64, y float64) {
+	b.x, b.y = x, y
+	for _, c := range b.children {
+		c.position(x+svgPadding+c.x, y+svgPadding+b.header()+c.y)
+	}
+}
+
+func (b *svgBox) write(buf *bytes.Buffer) {
+	color := getResourceColor(b.node.Data.Type)
+
+	if len(b.children) == 0 {
+		fill := "white"
+		if c, ok := changeColors[b.node.Data.Change]; ok {
+			fill = c
+		} else if b.node.Data.Drift {
+			fill = driftColor
+		}
+		stroke := ""
+		if b.node.Data.Drift {
+			stroke = ` stroke-width="2" stroke-dasharray="6 3"`
+		}
+		// Data sources are read, not changed
+		rx := 6.0
+		if b.node.Data.Type == ResourceTypeData {
+			rx = b.h / 2
+		}
+		fmt.Fprintf(buf, `<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" rx="%.0f" fill="%s" stroke="%s"%s/>`+"\n", b.x, b.y, b.w, b.h, rx, fill, color, stroke)
+		fmt.Fprintf(buf, `<text x="%.0f" y="%.0f" text-anchor="middle" dominant-baseline="central">%s</text>`+"\n", b.x+b.w/2, b.y+b.h/2, html.EscapeString(b.node.Data.ID))
+		return
+	}
+
+	fmt.Fprintf(buf, `<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" rx="6" fill="none" stroke="%s" stroke-dasharray="4 2"/>`+"\n", b.x, b.y, b.w, b.h, color)
+	fmt.Fprintf(buf, `<text x="%.0f" y="%.0f" font-weight="bold">%s</text>`+"\n", b.x+svgPadding, b.y+svgPadding+10, html.EscapeString(b.node.Data.Label))
+
+	for _, c := range b.children {
+		c.write(buf)
+	}
+}
+
+// graphRanks returns the length of the longest dependency chain from each node.
+// Nodes without dependencies have rank 0.
+func graphRanks(edges []Edge) map[string]int {
+	deps := make(map[string][]string)
+	for _, e := range edges {
+		deps[e.Data.Source] = append(deps[e.Data.Source], e.Data.Target)
+	}
+
+	ranks := make(map[string]int)
+	visiting := make(map[string]bool)
+
+	var rank func(id string) int
+	rank = func(id string) int {
+		if r, ok := ranks[id]; ok {
+			return r
+		}
+		// Break dependency cycles
+		if visiting[id] {
+			return 0
+		}
+		visiting[id] = true
+
+		r := 0
+		for _, d := range deps[id] {
+			if dr := rank(d) + 1; dr > r {
+				r = dr
+			}
+		}
+
+		visiting[id] = false
+		ranks[id] = r
+		return r
+	}
+
+	for id := range deps {
+		rank(id)
+	}
+
+	return ranks
+}
+
+func maxFloat(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package rover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// tfcClient returns a Terraform Cloud client authenticated with r.TFCToken
+// or the TFC_TOKEN environment variable
+func (r *Rover) tfcClient() (*tfe.Client, error) {
+	tfcToken := r.TFCToken
+	if tfcToken == "" {
+		tfcToken = os.Getenv("TFC_TOKEN")
+	}
+	if tfcToken == "" {
+		return nil, errors.New("Terraform Cloud token not set, use -tfcToken or the TFC_TOKEN environment variable")
+	}
+
+	client, err := tfe.NewClient(&tfe.Config{Token: tfcToken})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to connect to Terraform Cloud. %s", err))
+	}
+
+	return client, nil
+}
+
+// tfcRunPlan returns the ID of the finished plan of the -tfcRunID run
+func (r *Rover) tfcRunPlan(ctx context.Context, client *tfe.Client) (string, error) {
+	run, err := client.Runs.Read(ctx, r.TFCRunID)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Unable to retrieve run %s from Terraform Cloud. %s", r.TFCRunID, err))
+	}
+	if run.Plan == nil {
+		return "", errors.New(fmt.Sprintf("Run %s doesn't have a plan", r.TFCRunID))
+	}
+
+	plan, err := client.Plans.Read(ctx, run.Plan.ID)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Unable to retrieve plan %s of run %s from Terraform Cloud. %s", run.Plan.ID, r.TFCRunID, err))
+	}
+
+	switch plan.Status {
+	case tfe.PlanFinished:
+		return plan.ID, nil
+	case tfe.PlanErrored, tfe.PlanCanceled, tfe.PlanUnreachable:
+		return "", errors.New(fmt.Sprintf("Plan of run %s is %s", r.TFCRunID, plan.Status))
+	default:
+		return "", errors.New(fmt.Sprintf("Plan of run %s is %s, wait for it to finish", r.TFCRunID, plan.Status))
+	}
+}
+
+// generateTFCRunPlan retrieves the plan JSON of the -tfcRunID run
+func (r *Rover) generateTFCRunPlan(ctx context.Context) error {
+	slog.Debug("Retrieving Terraform Cloud run plan...", "run", r.TFCRunID)
+
+	client, err := r.tfcClient()
+	if err != nil {
+		return err
+	}
+
+	planID, err := r.tfcRunPlan(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	planBytes, err := client.Plans.JSONOutput(ctx, planID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to retrieve plan %s of run %s from Terraform Cloud. %s", planID, r.TFCRunID, err))
+	}
+	if len(planBytes) == 0 {
+		return errors.New(fmt.Sprintf("Empty plan. Check run %s's plan JSON is available", r.TFCRunID))
+	}
+
+	if err := json.Unmarshal(planBytes, &r.Plan); err != nil {
+		return errors.New(fmt.Sprintf("Unable to parse plan %s of run %s: %s", planID, r.TFCRunID, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package rover
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/terraform-exec/tfexec"
+)
+
+// tfVarOptions returns the plan options for r.TfVars. tfexec passes every
+// -var-file before every -var, so a -var that a later *.tfvars file assigns
+// is left out, which keeps the precedence Terraform gives them in order.
+func (r *Rover) tfVarOptions() []tfexec.PlanOption {
+	keep := make([]bool, len(r.TfVars))
+	// Variables assigned by a file after the current one
+	overridden := make(map[string]bool)
+	for i := len(r.TfVars) - 1; i >= 0; i-- {
+		v := r.TfVars[i]
+		if v.File {
+			for name := range r.fileVariables(v.Value) {
+				overridden[name] = true
+			}
+			keep[i] = true
+			continue
+		}
+
+		name, _, _ := strings.Cut(v.Value, "=")
+		keep[i] = !overridden[strings.TrimSpace(name)]
+		if !keep[i] {
+			slog.Debug("Skipping variable overridden by a later var file", "name", name)
+		}
+	}
+
+	var opts []tfexec.PlanOption
+	for i, v := range r.TfVars {
+		switch {
+		case v.Value == "" || !keep[i]:
+		case v.File:
+			opts = append(opts, tfexec.VarFile(v.Value))
+		default:
+			opts = append(opts, tfexec.Var(v.Value))
+		}
+	}
+
+	return opts
+}
+
+// fileVariables returns the variables a *.tfvars or *.tfvars.json file
+// assigns. Relative paths are in the working directory, where Terraform runs.
+func (r *Rover) fileVariables(path string) map[string]bool {
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(r.WorkingDir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	parser := hclparse.NewParser()
+	parse := parser.ParseHCLFile
+	if strings.HasSuffix(path, ".json") {
+		parse = parser.ParseJSONFile
+	}
+	// Terraform reports invalid files when planning
+	file, _ := parse(path)
+	if file == nil {
+		return nil
+	}
+	attrs, _ := file.Body.JustAttributes()
+
+	names := make(map[string]bool, len(attrs))
+	for name := range attrs {
+		names[name] = true
+	}
+	return names
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//go:embed schema/*.schema.json
+var schemas embed.FS
+
+// apiSchema serves the JSON Schema of an asset type
+func (s *server) apiSchema(w http.ResponseWriter, r *http.Request) {
+	s.rover().enableCors(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	schemaType := strings.TrimPrefix(r.URL.Path, "/api/schema/")
+
+	schema, err := schemas.ReadFile(fmt.Sprintf("schema/%s.schema.json", schemaType))
+	if err != nil || strings.Contains(schemaType, "/") {
+		writeError(w, http.StatusNotFound, "Please enter a valid schema type: rso, map, graph")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(schema)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"rover/pkg/rover"
+)
+
+// TestSchemas validates generated assets against the schemas served at
+// /api/schema/, so changes to either are caught
+func TestSchemas(t *testing.T) {
+	tests := []struct {
+		name   string
+		config rover.Config
+	}{
+		{"plan", rover.Config{PlanJSONPath: "plan.json"}},
+		{"drift", rover.Config{PlanJSONPath: "drift.json"}},
+		{"moved", rover.Config{PlanJSONPath: "moved.json"}},
+		{"diff", rover.Config{PlanJSONPath: "moved.json", ComparePlanPath: "plan.json"}},
+		{"collapsed", rover.Config{PlanJSONPath: "plan.json", CollapseThreshold: 1}},
+	}
+
+	v := newSchemaValidator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			c.Name = "rover"
+			c.WorkingDir = t.TempDir()
+			c.PlanJSONPath = filepath.Join("testdata", c.PlanJSONPath)
+			if c.ComparePlanPath != "" {
+				c.ComparePlanPath = filepath.Join("testdata", c.ComparePlanPath)
+			}
+
+			r := rover.New(c)
+			if err := r.Generate(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			for schemaType, asset := range map[string]interface{}{
+				"rso":   r.RSO,
+				"map":   r.Map,
+				"graph": r.Graph,
+			} {
+				for _, err := range v.validate(schemaType, asset) {
+					t.Errorf("%s: %s", schemaType, err)
+				}
+			}
+		})
+	}
+}
+
+// schemaValidator validates JSON against the subset of JSON Schema draft-07
+// the embedded schemas use. Other keywords fail validation so they aren't
+// silently ignored.
+type schemaValidator struct {
+	schemas map[string]map[string]interface{}
+	errs    []string
+}
+
+// Keywords that don't constrain values
+var schemaAnnotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"title":       true,
+	"description": true,
+	"definitions": true,
+}
+
+func newSchemaValidator(t *testing.T) *schemaValidator {
+	v := &schemaValidator{schemas: make(map[string]map[string]interface{})}
+
+	files, err := schemas.ReadDir("schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := schemas.ReadFile("schema/" + f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s map[string]interface{}
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatalf("%s: %s", f.Name(), err)
+		}
+		v.schemas[f.Name()] = s
+	}
+
+	return v
+}
+
+// validate returns the errors validating asset against the schemaType schema
+func (v *schemaValidator) validate(schemaType string, asset interface{}) []string {
+	b, err := json.Marshal(asset)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return []string{err.Error()}
+	}
+
+	file := schemaType + ".schema.json"
+	v.errs = nil
+	v.check(file, v.schemas[file], value, "")
+	return v.errs
+}
+
+func (v *schemaValidator) errorf(path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "/"
+	}
+	v.errs = append(v.errs, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// check validates value against schema, which is part of file
+func (v *schemaValidator) check(file string, schema map[string]interface{}, value interface{}, path string) {
+	keywords := make([]string, 0, len(schema))
+	for k := range schema {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+
+	for _, k := range keywords {
+		s := schema[k]
+		switch k {
+		case "$ref":
+			refFile, ref := v.resolve(file, s.(string))
+			if ref == nil {
+				v.errorf(path, "unresolved $ref %s", s)
+				continue
+			}
+			v.check(refFile, ref, value, path)
+		case "type":
+			types, ok := s.([]interface{})
+			if !ok {
+				types = []interface{}{s}
+			}
+			matched := false
+			for _, t := range types {
+				matched = matched || hasType(value, t.(string))
+			}
+			if !matched {
+				v.errorf(path, "%s is not of type %v", jsonType(value), s)
+			}
+		case "enum":
+			matched := false
+			for _, e := range s.([]interface{}) {
+				matched = matched || e == value
+			}
+			if !matched {
+				v.errorf(path, "%v is not one of %v", value, s)
+			}
+		case "required":
+			if o, ok := value.(map[string]interface{}); ok {
+				for _, p := range s.([]interface{}) {
+					if _, ok := o[p.(string)]; !ok {
+						v.errorf(path, "missing required property %s", p)
+					}
+				}
+			}
+		case "properties":
+			if o, ok := value.(map[string]interface{}); ok {
+				for p, ps := range s.(map[string]interface{}) {
+					if pv, ok := o[p]; ok {
+						v.check(file, ps.(map[string]interface{}), pv, path+"/"+p)
+					}
+				}
+			}
+		case "additionalProperties":
+			o, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			props, _ := schema["properties"].(map[string]interface{})
+			for p, pv := range o {
+				if _, ok := props[p]; ok {
+					continue
+				}
+				switch as := s.(type) {
+				case bool:
+					if !as {
+						v.errorf(path, "additional property %s is not allowed", p)
+					}
+				case map[string]interface{}:
+					v.check(file, as, pv, path+"/"+p)
+				}
+			}
+		case "items":
+			if a, ok := value.([]interface{}); ok {
+				for i, iv := range a {
+					v.check(file, s.(map[string]interface{}), iv, fmt.Sprintf("%s/%d", path, i))
+				}
+			}
+		default:
+			if !schemaAnnotations[k] {
+				v.errorf(path, "unsupported schema keyword %s", k)
+			}
+		}
+	}
+}
+
+// resolve returns the schema a $ref like #/definitions/node or
+// map.schema.json#/definitions/action refers to, and the file it's in
+func (v *schemaValidator) resolve(file string, ref string) (string, map[string]interface{}) {
+	refFile, pointer, _ := strings.Cut(ref, "#")
+	if refFile != "" {
+		file = refFile
+	}
+
+	var node interface{} = v.schemas[file]
+	for _, p := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if p == "" {
+			continue
+		}
+		o, ok := node.(map[string]interface{})
+		if !ok {
+			return file, nil
+		}
+		node = o[p]
+	}
+
+	s, _ := node.(map[string]interface{})
+	return file, s
+}
+
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	}
+	return jsonType(value) == t
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
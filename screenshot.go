@@ -4,8 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -14,8 +13,9 @@ import (
 	"github.com/chromedp/chromedp"
 )
 
+// screenshot saves the graph served at url to rover.svg
 // Heavily inspired by: https://github.com/chromedp/examples/blob/master/download_file/main.go
-func screenshot(s *http.Server) {
+func screenshot(url string) error {
 	// ctx, cancel := chromedp.NewContext(context.Background(), chromedp.WithDebugf(log.Printf))
 	ctx, cancel := chromedp.NewContext(context.Background())
 	defer cancel()
@@ -24,8 +24,6 @@ func screenshot(s *http.Server) {
 	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	url := fmt.Sprintf("http://%s", s.Addr)
-
 	// this will be used to capture the file name later
 	var downloadGUID string
 
@@ -52,19 +50,23 @@ func screenshot(s *http.Server) {
 	}); err != nil && !strings.Contains(err.Error(), "net::ERR_ABORTED") {
 		// Note: Ignoring the net::ERR_ABORTED page error is essential here since downloads
 		// will cause this error to be emitted, although the download will still succeed.
-		log.Fatal(err)
+		return fmt.Errorf("Unable to generate image: %s", err)
 	}
-	<-downloadComplete
 
-	e := moveFile(fmt.Sprintf("%v/%v", os.TempDir(), downloadGUID), "./rover.svg")
-	if e != nil {
-		log.Fatal(e)
+	select {
+	case <-downloadComplete:
+	case <-ctx.Done():
+		return fmt.Errorf("Unable to generate image: %s", ctx.Err())
 	}
 
-	log.Println("Image generation complete.")
+	err := moveFile(fmt.Sprintf("%v/%v", os.TempDir(), downloadGUID), "./rover.svg")
+	if err != nil {
+		return fmt.Errorf("Unable to generate image: %s", err)
+	}
 
-	// Shutdown http server
-	s.Shutdown(context.Background())
+	slog.Info("Image generation complete.")
+
+	return nil
 }
 
 // This function resolves the "invalid cross-device link" error for moving files
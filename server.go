@@ -1,79 +1,788 @@
 package main
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"rover/pkg/rover"
 	// tfjson "github.com/hashicorp/terraform-json"
 )
 
-func (ro *rover) startServer(ipPort string, frontendFS http.Handler) error {
+// Time to wait for in-flight requests before force closing the server
+const shutdownTimeout = 5 * time.Second
+
+// Time to read request headers, so slow clients can't hold connections open
+const readHeaderTimeout = 10 * time.Second
+
+// server serves the frontend and the generated assets
+type server struct {
+	handler http.Handler
+
+	// ro is swapped when the assets are regenerated
+	mu sync.RWMutex
+	ro *app
+
+	// In-flight regeneration that concurrent triggers wait for
+	regenMu  sync.Mutex
+	regenRun *regeneration
+
+	// Server-Sent Events subscribers, notified after regeneration
+	subMu       sync.Mutex
+	subscribers map[chan string]struct{}
+	// closed when the server shuts down so event streams end
+	done chan struct{}
+
+	// Configurations served under /api/{name}/, set when serving several
+	configs map[string]*server
+	names   []string
+}
+
+// newServer returns the handler for the frontend and Rover API. ro is served
+// under /api/ and each of configs, if any, under /api/{name}/.
+func newServer(ro *app, frontendFS http.Handler, configs []*app) *server {
+	s := &server{
+		ro:          ro,
+		subscribers: make(map[chan string]struct{}),
+		done:        make(chan struct{}),
+	}
+
+	if len(configs) > 0 {
+		s.configs = make(map[string]*server)
+		for _, c := range configs {
+			cs := s
+			if c != ro {
+				cs = &server{
+					ro:          c,
+					subscribers: make(map[chan string]struct{}),
+					done:        s.done,
+				}
+			}
+			s.configs[c.Name] = cs
+			s.names = append(s.names, c.Name)
+		}
+	}
 
 	m := http.NewServeMux()
-	s := http.Server{Addr: ipPort, Handler: m}
-
-	m.Handle("/", frontendFS)
-	m.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		// simple healthcheck
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/json")
-		io.WriteString(w, `{"alive": true}`)
+	if ro.APIOnly {
+		m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusNotFound, "Not found, only the API is served")
+		})
+	} else {
+		m.Handle("/", frontendFS)
+	}
+	m.HandleFunc("/health", s.health)
+	m.HandleFunc("/api/health", s.apiHealth)
+	m.HandleFunc("/api/ready", s.apiReady)
+	m.HandleFunc("/api/version", s.apiVersion)
+	m.HandleFunc("/api/events", s.apiEvents)
+	m.HandleFunc("/api/schema/", s.apiSchema)
+	m.HandleFunc("/api/configs", s.apiConfigs)
+	m.HandleFunc("/api/search", s.apiSearch)
+	m.HandleFunc("/api/regenerate", s.apiRegenerate)
+	m.HandleFunc("/api/dependents", s.apiDependents)
+	m.HandleFunc("/api/dependencies", s.apiDependencies)
+	m.HandleFunc("/api/", s.api)
+	if ro.metrics != nil {
+		m.Handle("/metrics", ro.metrics.handler())
+	}
+
+	s.handler = gzipHandler(m)
+	if ro.AuthToken != "" {
+		s.handler = authHandler(ro.AuthToken, s.handler)
+	}
+	s.handler = apiVersionHandler(s.handler)
+	if ro.metrics != nil {
+		s.handler = ro.metrics.requestsHandler(s.handler)
+	}
+	s.handler = logHandler(s.handler)
+	if ro.BasePath != "" {
+		s.handler = basePathHandler(ro.BasePath, s.handler)
+	}
+
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// rover returns the rover with the currently served assets
+func (s *server) rover() *app {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ro
+}
+
+// regeneration is a run of regenerate, done is closed when it finishes
+type regeneration struct {
+	done chan struct{}
+	err  error
+}
+
+// regenerate generates a new set of assets and swaps them in. The current
+// assets keep being served if generation fails. Calls made while assets are
+// being regenerated wait for that regeneration instead of starting another.
+func (s *server) regenerate(ctx context.Context) error {
+	s.regenMu.Lock()
+	run := s.regenRun
+	if run == nil {
+		run = &regeneration{done: make(chan struct{})}
+		s.regenRun = run
+
+		go func() {
+			run.err = s.generate(ctx)
+
+			s.regenMu.Lock()
+			s.regenRun = nil
+			s.regenMu.Unlock()
+			close(run.done)
+		}()
+	}
+	s.regenMu.Unlock()
+
+	<-run.done
+	return run.err
+}
+
+// generate generates a new set of assets and swaps them in
+func (s *server) generate(ctx context.Context) error {
+	next := s.rover().clone()
+
+	err := next.generateAssets(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.ro = next
+	s.mu.Unlock()
+
+	s.publish(next.etag)
+
+	return nil
+}
+
+// apiRegenerate regenerates the assets on POST, for CI and webhooks, and
+// returns the new meta and ETag
+func (s *server) apiRegenerate(w http.ResponseWriter, r *http.Request) {
+	ro := s.rover()
+	ro.enableCors(w, r)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "Use POST to regenerate assets")
+		return
+	}
+
+	// Anyone who can reach the server could otherwise run plans
+	if ro.AuthToken == "" {
+		writeError(w, http.StatusForbidden, "Regenerating assets requires -authToken")
+		return
+	}
+
+	// Plans can take longer than -writeTimeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("Unable to clear write deadline", "error", err)
+	}
+
+	slog.Info("Regeneration requested, regenerating assets...", "name", ro.Name)
+
+	// Coalesced requests share the regeneration, so it isn't canceled if
+	// this client disconnects
+	err := s.regenerate(context.WithoutCancel(r.Context()))
+	if err != nil {
+		slog.Error("Unable to regenerate assets", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	slog.Info("Done regenerating assets.")
+
+	ro = s.rover()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", ro.etag)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"etag": ro.etag,
+		"meta": ro.Meta,
+	})
+}
+
+// publish notifies event subscribers that new assets are available
+func (s *server) publish(etag string) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for ch := range s.subscribers {
+		// Drop the event if the subscriber hasn't consumed the previous one,
+		// it will reload the latest assets anyway
+		select {
+		case ch <- etag:
+		default:
+		}
+	}
+}
+
+// apiEvents streams a reload event to the browser whenever the assets are regenerated
+func (s *server) apiEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	ch := make(chan string, 1)
+
+	s.subMu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subMu.Unlock()
+
+	defer func() {
+		s.subMu.Lock()
+		delete(s.subscribers, ch)
+		s.subMu.Unlock()
+	}()
+
+	// Event streams stay open for longer than -writeTimeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("Unable to clear write deadline for event stream", "error", err)
+	}
+
+	s.rover().enableCors(w, r)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case etag := <-ch:
+			fmt.Fprintf(w, "event: reload\ndata: {\"etag\":%q}\n\n", strings.Trim(etag, `"`))
+			flusher.Flush()
+		}
+	}
+}
+
+// health is a simple healthcheck
+func (s *server) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, `{"alive": true}`)
+}
+
+// apiHealth is a liveness check that doesn't touch the assets
+func (s *server) apiHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, `{"status":"ok"}`)
+}
+
+// apiReady is a readiness check that succeeds once the assets are generated
+func (s *server) apiReady(w http.ResponseWriter, r *http.Request) {
+	if !s.rover().Ready {
+		writeError(w, http.StatusServiceUnavailable, "Assets have not been generated")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, `{"status":"ready"}`)
+}
+
+// apiVersion returns the Rover and API versions
+func (s *server) apiVersion(w http.ResponseWriter, r *http.Request) {
+	s.rover().enableCors(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"version":     VERSION,
+		"api_version": API_VERSION,
+	})
+}
+
+// apiConfigs lists the names of the served configurations
+func (s *server) apiConfigs(w http.ResponseWriter, r *http.Request) {
+	names := s.names
+	if names == nil {
+		names = []string{s.rover().Name}
+	}
+
+	s.rover().enableCors(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{"configs": names})
+}
+
+// apiSearch returns the resources matching the q query parameter
+func (s *server) apiSearch(w http.ResponseWriter, r *http.Request) {
+	ro := s.rover()
+	ro.enableCors(w, r)
+
+	q := r.URL.Query().Get("q")
+	if strings.TrimSpace(q) == "" {
+		writeError(w, http.StatusBadRequest, "Missing search query q")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   q,
+		"results": ro.Search(q),
 	})
-	m.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
-		fileType := strings.Replace(r.URL.Path, "/api/", "", 1)
+}
 
-		var j []byte
+// apiDependents returns the nodes that depend on the addr query parameter
+func (s *server) apiDependents(w http.ResponseWriter, r *http.Request) {
+	s.apiTraverse(w, r, "dependents", s.rover().Dependents)
+}
+
+// apiDependencies returns the nodes the addr query parameter depends on
+func (s *server) apiDependencies(w http.ResponseWriter, r *http.Request) {
+	s.apiTraverse(w, r, "dependencies", s.rover().Dependencies)
+}
+
+// apiTraverse serves the result of traversing the graph from addr, limited
+// by the optional depth query parameter
+func (s *server) apiTraverse(w http.ResponseWriter, r *http.Request, key string, traverse func(string, int) ([]rover.DependencyResult, error)) {
+	s.rover().enableCors(w, r)
+
+	q := r.URL.Query()
+	addr := strings.TrimSpace(q.Get("addr"))
+	if addr == "" {
+		writeError(w, http.StatusBadRequest, "Missing resource address addr")
+		return
+	}
+
+	depth := 0
+	if d := q.Get("depth"); d != "" {
 		var err error
+		depth, err = strconv.Atoi(d)
+		if err != nil || depth < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid depth %q: must be a non-negative integer", d))
+			return
+		}
+	}
 
-		enableCors(&w)
+	results, err := traverse(addr, depth)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
 
-		switch fileType {
-		case "plan":
-			j, err = json.Marshal(ro.Plan)
-			if err != nil {
-				io.WriteString(w, fmt.Sprintf("Error producing plan JSON: %s\n", err))
-			}
-		case "rso":
-			j, err = json.Marshal(ro.RSO)
-			if err != nil {
-				io.WriteString(w, fmt.Sprintf("Error producing rso JSON: %s\n", err))
-			}
-		case "map":
-			j, err = json.Marshal(ro.Map)
-			if err != nil {
-				io.WriteString(w, fmt.Sprintf("Error producing map JSON: %s\n", err))
-			}
-		case "graph":
-			j, err = json.Marshal(ro.Graph)
-			if err != nil {
-				io.WriteString(w, fmt.Sprintf("Error producing graph JSON: %s\n", err))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"address": addr,
+		"depth":   depth,
+		key:       results,
+	})
+}
+
+// config serves /api/{name}/{path} from the named configuration's server
+func (s *server) config(w http.ResponseWriter, r *http.Request) bool {
+	name, path, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/"), "/")
+	if !ok {
+		return false
+	}
+	cs, ok := s.configs[name]
+	if !ok {
+		return false
+	}
+
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = "/api/" + path
+	r2.URL.RawPath = ""
+
+	switch path {
+	case "events":
+		cs.apiEvents(w, r2)
+	case "ready":
+		cs.apiReady(w, r2)
+	case "search":
+		cs.apiSearch(w, r2)
+	case "regenerate":
+		cs.apiRegenerate(w, r2)
+	case "dependents":
+		cs.apiDependents(w, r2)
+	case "dependencies":
+		cs.apiDependencies(w, r2)
+	default:
+		cs.api(w, r2)
+	}
+
+	return true
+}
+
+func (s *server) api(w http.ResponseWriter, r *http.Request) {
+	if s.configs != nil && s.config(w, r) {
+		return
+	}
+
+	ro := s.rover()
+	fileType := strings.Replace(r.URL.Path, "/api/", "", 1)
+
+	// Set headers before anything is written to the body
+	ro.enableCors(w, r)
+
+	// CORS preflight
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// ?hideNoOp overrides -hideNoOp
+	hideNoOp := ro.HideNoOp
+	if q := r.URL.Query().Get("hideNoOp"); q != "" {
+		var err error
+		hideNoOp, err = strconv.ParseBool(q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid hideNoOp value: %s", q))
+			return
+		}
+	}
+
+	cache := ro.cache
+	if hideNoOp {
+		cache = ro.hiddenCache
+	}
+
+	// ?changed=true serves the plan without no-op resource changes
+	if q := r.URL.Query().Get("changed"); q != "" && fileType == "plan" {
+		changed, err := strconv.ParseBool(q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid changed value: %s", q))
+			return
+		}
+		if changed {
+			cache = map[string]*cachedAsset{"plan": ro.changedPlan}
+		}
+	}
+
+	// ?expand lists collapsed resource groups to expand in the graph
+	if expand := r.URL.Query().Get("expand"); expand != "" && strings.HasPrefix(fileType, "graph") {
+		er := ro.ExpandGroups(strings.Split(expand, ","))
+		if hideNoOp {
+			er = er.WithoutNoOp()
+		}
+
+		// Expanded graphs aren't cached, so the graph is encoded without an ETag
+		if fileType == "graph" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-cache")
+			if err := json.NewEncoder(w).Encode(er.Graph); err != nil {
+				slog.Warn("Unable to write graph", "error", err)
 			}
-		default:
-			io.WriteString(w, "Please enter a valid file type: plan, rso, map, graph\n")
+			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		io.Copy(w, bytes.NewReader(j))
-	})
+		cache = make(map[string]*cachedAsset)
+		if err := cacheGraphAssets(er, cache); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	asset, ok := cache[fileType]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Please enter a valid file type: plan, rso, map, graph, meta, providers, order, diff, graph.dot, graph.mmd, graph.svg")
+		return
+	}
+
+	w.Header().Set("Content-Type", asset.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, assetFilename(ro.Name, fileType)))
+
+	// Browsers revalidate with If-None-Match instead of downloading the asset again
+	w.Header().Set("ETag", asset.etag)
+	w.Header().Set("Cache-Control", "no-cache")
 
-	log.Printf("Rover is running on %s", ipPort)
+	if etagMatches(r.Header.Get("If-None-Match"), asset.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := asset.write(w); err != nil {
+		slog.Warn("Unable to write asset", "fileType", fileType, "error", err)
+	}
+}
+
+// assetFilename returns the file name an asset is saved as, prefixed with the
+// configuration name, like rover-graph.svg
+func assetFilename(name string, fileType string) string {
+	if path.Ext(fileType) == "" {
+		fileType += ".json"
+	}
+	return fmt.Sprintf("%s-%s", name, fileType)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// cachedAsset is an asset's JSON, marshaled once after generation. The plan
+// and graph can be large, so they're encoded straight to each response
+// instead of keeping their JSON in memory.
+type cachedAsset struct {
+	body []byte
+	// Encoded on each request if set, instead of body
+	value       interface{}
+	etag        string
+	contentType string
+}
+
+// write writes the asset's JSON to w
+func (a *cachedAsset) write(w io.Writer) error {
+	if a.value == nil {
+		_, err := w.Write(a.body)
+		return err
+	}
+	return json.NewEncoder(w).Encode(a.value)
+}
+
+// cacheAssets marshals the generated assets so they aren't marshaled on every request
+// hidden is a copy of r without no-op resources, served with ?hideNoOp=true
+func (r *app) cacheAssets(hidden *rover.Rover) error {
+	cache := make(map[string]*cachedAsset)
+	hiddenCache := make(map[string]*cachedAsset)
+
+	assets := map[string]interface{}{
+		"rso":       r.RSO,
+		"meta":      r.Meta,
+		"providers": r.Providers,
+		"order":     r.Order(),
+	}
+	if r.Diff != nil {
+		assets["diff"] = r.Diff
+	}
+	if r.Legend != nil {
+		assets["legend"] = r.Legend
+	}
+	for fileType, asset := range assets {
+		j, err := json.Marshal(asset)
+		if err != nil {
+			return fmt.Errorf("Error producing %s JSON: %s", fileType, err)
+		}
+
+		cache[fileType] = newCachedAsset(j, "application/json")
+		hiddenCache[fileType] = cache[fileType]
+	}
+
+	plan, err := newStreamedAsset(r.Plan)
+	if err != nil {
+		return fmt.Errorf("Error producing plan JSON: %s", err)
+	}
+	cache["plan"] = plan
+	hiddenCache["plan"] = plan
+
+	r.changedPlan, err = newStreamedAsset(r.ChangedPlan())
+	if err != nil {
+		return fmt.Errorf("Error producing changed plan JSON: %s", err)
+	}
+
+	err = cacheGraphAssets(r.Rover, cache)
+	if err != nil {
+		return err
+	}
+
+	err = cacheGraphAssets(hidden, hiddenCache)
+	if err != nil {
+		return err
+	}
+
+	// Identifies the full set of assets
+	h := sha256.New()
+	for _, fileType := range []string{"plan", "rso", "map", "graph", "meta"} {
+		io.WriteString(h, cache[fileType].etag)
+		io.WriteString(h, hiddenCache[fileType].etag)
+	}
+
+	r.cache = cache
+	r.hiddenCache = hiddenCache
+	r.etag = fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+
+	return nil
+}
+
+// cacheGraphAssets adds r's map, graph and graph exports to cache
+func cacheGraphAssets(r *rover.Rover, cache map[string]*cachedAsset) error {
+	j, err := json.Marshal(r.Map)
+	if err != nil {
+		return fmt.Errorf("Error producing map JSON: %s", err)
+	}
+	cache["map"] = newCachedAsset(j, "application/json")
+
+	cache["graph"], err = newStreamedAsset(r.Graph)
+	if err != nil {
+		return fmt.Errorf("Error producing graph JSON: %s", err)
+	}
+
+	// Graph exports
+	cache["graph.dot"] = newCachedAsset(r.GenerateDOT(), "text/vnd.graphviz")
+	cache["graph.mmd"] = newCachedAsset(r.GenerateMermaid(), "text/vnd.mermaid")
+	cache["graph.svg"] = newCachedAsset(r.GenerateSVG(), "image/svg+xml")
+
+	return nil
+}
+
+func newCachedAsset(body []byte, contentType string) *cachedAsset {
+	sum := sha256.Sum256(body)
+	return &cachedAsset{
+		body:        body,
+		etag:        fmt.Sprintf(`"%x"`, sum[:16]),
+		contentType: contentType,
+	}
+}
+
+// newStreamedAsset returns an asset encoded on each request. Its ETag is
+// hashed from the encoding, which isn't kept.
+func newStreamedAsset(value interface{}) (*cachedAsset, error) {
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(value); err != nil {
+		return nil, err
+	}
+	return &cachedAsset{
+		value:       value,
+		etag:        fmt.Sprintf(`"%x"`, h.Sum(nil)[:16]),
+		contentType: "application/json",
+	}, nil
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func (ro *app) startServer(ipPort string, frontendFS http.Handler, configs []*app) error {
+
+	srv := newServer(ro, frontendFS, configs)
+	s := http.Server{
+		Addr:              ipPort,
+		Handler:           srv,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       ro.ReadTimeout,
+		WriteTimeout:      ro.WriteTimeout,
+		IdleTimeout:       ro.IdleTimeout,
+	}
+	s.RegisterOnShutdown(func() { close(srv.done) })
 
 	l, err := net.Listen("tcp", ipPort)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("Could not start server: %s", err)
+	}
+
+	scheme := "http"
+	if ro.TLSCert != "" {
+		scheme = "https"
+	}
+
+	if ro.APIOnly {
+		slog.Info(fmt.Sprintf("Rover API is running on %s://%s%s/api/", scheme, ipPort, ro.BasePath))
+	} else {
+		slog.Info(fmt.Sprintf("Rover is running on %s://%s%s/", scheme, ipPort, ro.BasePath))
 	}
 
 	// The browser can connect now because the listening socket is open.
+	imageErr := make(chan error, 1)
 	if ro.GenImage {
-		go screenshot(&s)
+		url := fmt.Sprintf("%s://%s%s", scheme, ipPort, ro.BasePath)
+		if ro.AuthToken != "" {
+			url = fmt.Sprintf("%s/?token=%s", url, neturl.QueryEscape(ro.AuthToken))
+		}
+		go func() {
+			imageErr <- screenshot(url)
+			s.Shutdown(context.Background())
+		}()
+	}
+
+	// Drain in-flight requests on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if ro.Watch {
+		watched := []*server{srv}
+		for _, cs := range srv.configs {
+			if cs != srv {
+				watched = append(watched, cs)
+			}
+		}
+		for _, ws := range watched {
+			go func(ws *server) {
+				dir := ws.rover().WorkingDir
+				if err := ws.watch(ctx, dir); err != nil {
+					slog.Error("Unable to watch working directory", "workingDir", dir, "error", err)
+				}
+			}(ws)
+		}
 	}
 
+	serveDone := make(chan struct{})
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+
+		select {
+		case <-ctx.Done():
+		case <-serveDone:
+			return
+		}
+
+		slog.Info("Shutting down server...")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Forcing server to close", "error", err)
+			s.Close()
+		}
+	}()
+
 	// Start the blocking server loop.
-	return s.Serve(l)
+	// http.Serve() returns ErrServerClosed on shutdown
+	if ro.TLSCert != "" {
+		err = s.ServeTLS(l, ro.TLSCert, ro.TLSKey)
+	} else {
+		err = s.Serve(l)
+	}
+	close(serveDone)
+	<-shutdownDone
+
+	if err != http.ErrServerClosed {
+		return fmt.Errorf("Could not start server: %s", err)
+	}
+
+	if ro.GenImage {
+		return <-imageErr
+	}
+
+	return nil
 
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rover/pkg/rover"
+)
+
+// TestStreamedAssets checks the plan and graph, which are encoded on each
+// request rather than cached, are served whole with an ETag
+func TestStreamedAssets(t *testing.T) {
+	r := &app{Rover: rover.New(rover.Config{
+		Name:         "rover",
+		WorkingDir:   t.TempDir(),
+		PlanJSONPath: filepath.Join("testdata", "plan.json"),
+	})}
+	if err := r.generateAssets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(newServer(r, http.NotFoundHandler(), nil))
+	defer ts.Close()
+
+	for fileType, asset := range map[string]interface{}{
+		"plan":  r.Plan,
+		"graph": r.Graph,
+	} {
+		want, err := json.Marshal(asset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The UI's fetch accepts gzip, which Go's client decodes
+		for _, encoding := range []string{"identity", ""} {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/"+fileType, nil)
+			if encoding != "" {
+				req.Header.Set("Accept-Encoding", encoding)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("%s: %s", fileType, err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s: status %d: %s", fileType, resp.StatusCode, body)
+			}
+			// net/http buffers 2048 bytes before it chunks a response
+			if encoding == "identity" && len(want) > 2048 && !reflect.DeepEqual(resp.TransferEncoding, []string{"chunked"}) {
+				t.Errorf("%s: transfer encoding %v, want chunked", fileType, resp.TransferEncoding)
+			}
+			if !jsonEqual(t, body, want) {
+				t.Errorf("%s (Accept-Encoding %q): body doesn't match the asset:\n%s", fileType, encoding, body)
+			}
+
+			etag := resp.Header.Get("ETag")
+			if etag != r.cache[fileType].etag {
+				t.Fatalf("%s: ETag %s, want %s", fileType, etag, r.cache[fileType].etag)
+			}
+
+			req.Header.Set("If-None-Match", etag)
+			resp, err = http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotModified {
+				t.Errorf("%s: status %d with If-None-Match, want %d", fileType, resp.StatusCode, http.StatusNotModified)
+			}
+		}
+	}
+}
+
+func jsonEqual(t *testing.T, a []byte, b []byte) bool {
+	var av, bv interface{}
+	if err := json.Unmarshal(a, &av); err != nil {
+		t.Errorf("invalid JSON: %s", err)
+		return false
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		t.Errorf("invalid JSON: %s", err)
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func TestAPIDependents(t *testing.T) {
+	r := &app{Rover: rover.New(rover.Config{
+		Name:         "rover",
+		WorkingDir:   t.TempDir(),
+		PlanJSONPath: filepath.Join("testdata", "plan.json"),
+	})}
+	if err := r.generateAssets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(newServer(r, http.NotFoundHandler(), nil))
+	defer ts.Close()
+
+	tests := []struct {
+		query  string
+		status int
+		addr   string
+		depth  int
+	}{
+		{query: "addr=local.prefix", status: http.StatusOK, addr: "local.prefix"},
+		{query: "addr=local.prefix&depth=1", status: http.StatusOK, addr: "local.prefix", depth: 1},
+		// Instances without a node of their own use their resource's
+		{query: "addr=random_pet.web[5]", status: http.StatusOK, addr: "random_pet.web[5]"},
+		{query: "", status: http.StatusBadRequest},
+		{query: "addr=local.prefix&depth=-1", status: http.StatusBadRequest},
+		{query: "addr=local.prefix&depth=all", status: http.StatusBadRequest},
+		{query: "addr=random_pet.missing", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get(ts.URL + "/api/dependents?" + tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("%q: status %d, want %d: %s", tt.query, resp.StatusCode, tt.status, body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+
+		results, err := r.Dependents(tt.addr, tt.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) == 0 {
+			t.Fatalf("%q: no dependents to compare", tt.query)
+		}
+		want, err := json.Marshal(map[string]interface{}{
+			"address":    tt.addr,
+			"depth":      tt.depth,
+			"dependents": results,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !jsonEqual(t, body, want) {
+			t.Errorf("%q: body %s, want %s", tt.query, body, want)
+		}
+	}
+}
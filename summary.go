@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"rover/pkg/rover"
+)
+
+// planSummary is the one-line JSON summary printed for CI in headless mode
+type planSummary struct {
+	Add        int               `json:"add"`
+	Change     int               `json:"change"`
+	Destroy    int               `json:"destroy"`
+	Replace    int               `json:"replace"`
+	Complexity *rover.Complexity `json:"complexity,omitempty"`
+}
+
+func newPlanSummary(r *rover.Rover) planSummary {
+	var summary planSummary
+	if r.RSO != nil && r.RSO.Summary != nil {
+		s := r.RSO.Summary
+		summary = planSummary{
+			Add:     s.Create,
+			Change:  s.Update,
+			Destroy: s.Delete,
+			Replace: s.Replace,
+		}
+	}
+
+	// The chain's path would make the summary line too long
+	if r.Meta != nil && r.Meta.Complexity != nil {
+		c := *r.Meta.Complexity
+		c.LongestChainPath = nil
+		summary.Complexity = &c
+	}
+
+	return summary
+}
+
+// JSON returns the summary as a single line of JSON
+func (s planSummary) JSON() []byte {
+	b, _ := json.Marshal(s)
+	return append(b, '\n')
+}
+
+// Changes -failOn can fail on
+var failOnKinds = []string{"add", "change", "destroy", "replace", "any"}
+
+// parseFailOn parses a comma-separated list of changes to fail on
+func parseFailOn(failOn string) ([]string, error) {
+	var changes []string
+	for _, c := range strings.Split(failOn, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+
+		valid := false
+		for _, fc := range failOnKinds {
+			valid = valid || c == fc
+		}
+		if !valid {
+			return nil, errors.New(fmt.Sprintf("Invalid failOn %s: must be one of %s", c, strings.Join(failOnKinds, ", ")))
+		}
+
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// failed returns the first of changes the plan has, if any
+func (s planSummary) failed(changes []string) (string, bool) {
+	counts := map[string]int{
+		"add":     s.Add,
+		"change":  s.Change,
+		"destroy": s.Destroy,
+		"replace": s.Replace,
+		"any":     s.Add + s.Change + s.Destroy + s.Replace,
+	}
+	for _, c := range changes {
+		if counts[c] > 0 {
+			return c, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Wait for changes to settle so a burst of saves triggers one regeneration
+const watchDebounce = 500 * time.Millisecond
+
+// watch regenerates the assets when *.tf or *.tfvars files in dir change
+func (s *server) watch(ctx context.Context, dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	_, err = watchDirs(watcher, dir)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Watching for changes...", "dir", dir)
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if isWatchedFile(event.Name) {
+				debounce.Reset(watchDebounce)
+			}
+			// Watch new directories, like modules, which may already have
+			// files if they were moved or copied in
+			if event.Op&fsnotify.Create != 0 && !isHidden(event.Name) {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					found, err := watchDirs(watcher, event.Name)
+					if err != nil {
+						slog.Warn("Unable to watch directory", "dir", event.Name, "error", err)
+					}
+					if found {
+						debounce.Reset(watchDebounce)
+					}
+				}
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("Watch error", "error", err)
+		case <-debounce.C:
+			slog.Info("Change detected, regenerating assets...")
+			if err := s.regenerate(ctx); err != nil {
+				slog.Error("Unable to regenerate assets", "error", err)
+				continue
+			}
+			slog.Info("Done regenerating assets.")
+		}
+	}
+}
+
+// watchDirs adds dir and its subdirectories to watcher, and reports whether
+// they have watched files
+func watchDirs(watcher *fsnotify.Watcher, dir string) (bool, error) {
+	found := false
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			found = found || isWatchedFile(path)
+			return nil
+		}
+		// Skip .terraform and other hidden directories
+		if path != dir && isHidden(path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+	return found, err
+}
+
+func isHidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+func isWatchedFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tfvars")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rover/pkg/rover"
+)
+
+// TestWatchNewDirs checks changes in directories created after watching
+// started, like new modules, regenerate the assets
+func TestWatchNewDirs(t *testing.T) {
+	planJSONPath, err := filepath.Abs(filepath.Join("testdata", "plan.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &app{Rover: rover.New(rover.Config{
+		Name:         "rover",
+		WorkingDir:   dir,
+		PlanJSONPath: planJSONPath,
+	})}
+	if err := r.generateAssets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(r, http.NotFoundHandler(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.watch(ctx, dir)
+	time.Sleep(100 * time.Millisecond)
+
+	// waitRegenerated waits for the served assets to change from ro's
+	waitRegenerated := func(ro *app, change string) *app {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			if next := s.rover(); next != ro {
+				return next
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("assets weren't regenerated after %s", change)
+		return nil
+	}
+
+	// Nested directories are created before the parent is watched
+	if err := os.MkdirAll(filepath.Join(dir, "modules", "network"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "modules", "network", "main.tf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ro := waitRegenerated(r, "writing a file in a new directory")
+
+	// A module moved in already has its files
+	module := filepath.Join(t.TempDir(), "database")
+	if err := os.Mkdir(module, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(module, "main.tf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(module, filepath.Join(dir, "modules", "database")); err != nil {
+		t.Fatal(err)
+	}
+	waitRegenerated(ro, "moving a module in")
+}
@@ -7,12 +7,11 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
 	"os"
 	"strings"
 )
 
-func (r *rover) generateZip(fe fs.FS, filename string) error {
+func (r *app) generateZip(fe fs.FS, filename string) error {
 	newZipFile, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -25,7 +24,7 @@ func (r *rover) generateZip(fe fs.FS, filename string) error {
 	// Add frontend to zip file
 	feItems, err := fs.ReadDir(fe, ".")
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	for _, feItem := range feItems {
@@ -88,6 +87,9 @@ func AddEmbeddedToZip(fe fs.FS, zipWriter *zip.Writer, filename string) error {
 		content = strings.ReplaceAll(content, "=\"/", "=\"./")
 
 		tempFileName, tempFile, err := createTempFile("temp-index.html", []byte(content))
+		if err != nil {
+			return err
+		}
 		defer os.Remove(tempFile.Name()) // clean up
 		defer tempFile.Close()
 
@@ -105,6 +107,9 @@ func AddEmbeddedToZip(fe fs.FS, zipWriter *zip.Writer, filename string) error {
 		rawContent := bytes.ReplaceAll(curContent, []byte("r.p+\""), []byte("\"./"))
 
 		tempFileName, tempFile, err := createTempFile("temp-index.html", rawContent)
+		if err != nil {
+			return err
+		}
 		defer os.Remove(tempFile.Name()) // clean up
 		defer tempFile.Close()
 
@@ -143,6 +148,9 @@ func AddFileToZip(zipWriter *zip.Writer, fileType string, j interface{}) error {
 	content := fmt.Sprintf("const %s = %s", fileType, string(b))
 
 	tempFileName, tempFile, err := createTempFile(filename, []byte(content))
+	if err != nil {
+		return err
+	}
 	defer os.Remove(tempFile.Name()) // clean up
 	defer tempFile.Close()
 
@@ -159,7 +167,7 @@ func AddFileToZip(zipWriter *zip.Writer, fileType string, j interface{}) error {
 func createTempFile(filename string, b []byte) (string, *os.File, error) {
 	tempFile, err := os.CreateTemp("", filename)
 	if err != nil {
-		log.Fatal(err)
+		return "", nil, err
 	}
 
 	_, err = tempFile.Write(b)